@@ -0,0 +1,74 @@
+/*
+Package businessHours reads the business hours the teams give us for their nodes, like "07H00-19H00", or "24 X 7" for
+nodes that are always in business hours. A range where the end is before the start runs past midnight.
+*/
+package businessHours
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const allDay = "24 X 7"
+
+type Hours struct {
+	always     bool
+	start, end int
+}
+
+// Always is business hours all day, every day
+var Always = Hours{always: true}
+
+func Parse(hours string) (h Hours, err error) {
+	hours = strings.ToUpper(strings.TrimSpace(hours))
+	if hours == allDay {
+		return Always, nil
+	}
+	times := strings.Split(hours, "-")
+	if len(times) != 2 {
+		return h, fmt.Errorf("%v is not business hours, use 07H00-19H00 or 24 X 7", hours)
+	}
+	h.start, err = parseTime(times[0])
+	if err != nil {
+		return
+	}
+	h.end, err = parseTime(times[1])
+	return
+}
+
+// parseTime reads 07H00, or only the hour, 07H, into minutes after midnight
+func parseTime(s string) (int, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), "H", 2)
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 24 {
+		return 0, fmt.Errorf("%v is not a time, use 07H00", s)
+	}
+	minute := 0
+	if len(parts) == 2 && parts[1] != "" {
+		minute, err = strconv.Atoi(parts[1])
+		if err != nil || minute < 0 || minute > 59 {
+			return 0, fmt.Errorf("%v is not a time, use 07H00", s)
+		}
+	}
+	return hour*60 + minute, nil
+}
+
+func (h Hours) Contains(t time.Time) bool {
+	if h.always {
+		return true
+	}
+	now := t.Hour()*60 + t.Minute()
+	if h.end > h.start {
+		return now >= h.start && now < h.end
+	}
+	return now >= h.start || now < h.end
+}
+
+func (h Hours) String() string {
+	if h.always {
+		return allDay
+	}
+	return fmt.Sprintf("%02dH%02d-%02dH%02d", h.start/60, h.start%60, h.end/60, h.end%60)
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"os"
@@ -9,6 +11,8 @@ import (
 	httptransport "github.com/go-openapi/runtime/client"
 	"github.com/weAutomateEverything/go2hal/database"
 	"github.com/weAutomateEverything/prognosisHalBot/monitor"
+	"github.com/weAutomateEverything/prognosisHalBot/notifier"
+	"github.com/weAutomateEverything/prognosisHalBot/silence"
 	"github.com/weAutomateEverything/prognosisHalBot/sourceMonitor"
 	"net/http"
 	"os/signal"
@@ -22,6 +26,13 @@ import (
 )
 
 func main() {
+	//The config server, HAL and the other internal endpoints use certificates we can not verify. Every client that
+	//does not set its own transport gets this one, the Prognosis client sets the same on its own
+	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		os.Exit(validateConfig(os.Args[2:]))
+	}
 
 	var logger log.Logger
 	logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
@@ -36,13 +47,20 @@ func main() {
 	transport.SetDebug(true)
 	transport.SetLogger(logger2.StandardLogger{})
 
-	monitor.NewService(monitorStore, monitor.NewResponseCode91Monitor(), monitor.NewFailureRateMonitor(),
-		sourceMonitor.NewSourceSinkMonitor(sourceStore), sinkBin.NewSinkBinMonitor())
+	silenceStore := silence.NewMongoStore(db)
+	monitorService := monitor.NewService(monitorStore, silenceStore, notifier.FromEnvironment(), monitors(sourceStore)...)
+	silenceService := silence.NewService(silenceStore, monitorService)
 
 	httpLogger := log.With(logger, "component", "http")
 
 	mux := http.NewServeMux()
 	mux.Handle("/sourceMonitor/", sourceMonitor.MakeHandler(sourceStore, httpLogger))
+	monitorHandler := monitor.MakeHandler(monitorService, httpLogger)
+	mux.Handle("/monitor/", monitorHandler)
+	mux.Handle("/reports/", monitorHandler)
+	silenceHandler := silence.MakeHandler(silenceService, httpLogger)
+	mux.Handle("/monitor/silence", silenceHandler)
+	mux.Handle("/monitor/silence/", silenceHandler)
 	http.Handle("/", accessControl(mux))
 	http.Handle("/api/metrics", promhttp.Handler())
 
@@ -55,14 +73,46 @@ func main() {
 	}()
 
 	go func() {
-		c := make(chan os.Signal)
+		c := make(chan os.Signal, 1)
 		signal.Notify(c, syscall.SIGINT)
 		errs <- fmt.Errorf("%s", <-c)
 	}()
+
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, syscall.SIGHUP)
+		for range c {
+			logger.Log("signal", "SIGHUP", "msg", "reloading config")
+			monitorService.ReloadConfig(context.Background())
+		}
+	}()
 	logger.Log("terminated", <-errs)
 
 }
 
+func monitors(sourceStore sourceMonitor.Store) []monitor.Monitor {
+	return []monitor.Monitor{monitor.NewResponseCode91Monitor(), monitor.NewFailureRateMonitor(), monitor.NewCrossSiteMonitor(),
+		sourceMonitor.NewSourceSinkMonitor(sourceStore), sinkBin.NewSinkBinMonitor()}
+}
+
+/*
+validateConfig checks a config file or url without starting the bot, so config changes can be checked before they are
+merged. Usage: prognosisHalBot validate-config <file|url>
+*/
+func validateConfig(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: prognosisHalBot validate-config <file|url>")
+		return 2
+	}
+	err := monitor.ValidateConfig(args[0], notifier.FromEnvironment(), monitors(nil)...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("%v is valid\n", args[0])
+	return 0
+}
+
 func accessControl(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
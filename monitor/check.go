@@ -0,0 +1,107 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+/*
+Check is what a Monitor implementation gets to know about the monitor it is checking. Params come from the monitor's
+config entry, so each dashboard can tune its own thresholds, and History stores samples against the monitor.
+*/
+type Check struct {
+	Config  Config
+	Params  Params
+	Log     *log.Logger
+	History History
+}
+
+// Config is the config entry of the monitor being checked
+type Config struct {
+	Name, Type, Dashboard, Id, ObjectType string
+	Group                                 int64
+}
+
+func (s *service) newCheck(m *monitors) Check {
+	return Check{
+		Config: Config{
+			Name:       m.Name,
+			Type:       m.Type,
+			Dashboard:  m.Dashboard,
+			Id:         m.Id,
+			ObjectType: m.ObjectType,
+			Group:      m.Group,
+		},
+		Params:  m.Params,
+		Log:     log.New(log.Writer(), fmt.Sprintf("%v: ", m.Name), log.Flags()),
+		History: history{store: s.store, monitor: m.Name, dashboard: m.Dashboard},
+	}
+}
+
+// ParamsValidator is implemented by Monitor types that take Params, so validate-config can report bad ones
+type ParamsValidator interface {
+	ValidateParams(p Params) error
+}
+
+// Params is the free form Params object of a monitor's config entry
+type Params map[string]interface{}
+
+// Float returns the number called name, or def if it is not set. JSON numbers are always floats
+func (p Params) Float(name string, def float64) float64 {
+	v, ok := p[name]
+	if !ok {
+		return def
+	}
+	f, ok := v.(float64)
+	if !ok {
+		log.Printf("Param %v is %v, which is not a number. Using %v", name, v, def)
+		return def
+	}
+	return f
+}
+
+func (p Params) Int(name string, def int) int {
+	return int(p.Float(name, float64(def)))
+}
+
+func (p Params) String(name string, def string) string {
+	v, ok := p[name]
+	if !ok {
+		return def
+	}
+	s, ok := v.(string)
+	if !ok {
+		log.Printf("Param %v is %v, which is not a string. Using %v", name, v, def)
+		return def
+	}
+	return s
+}
+
+// Duration returns the duration called name, written like 30s or 5m, or def if it is not set
+func (p Params) Duration(name string, def time.Duration) time.Duration {
+	v := p.String(name, "")
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Param %v is %v, which is not a duration. Using %v", name, v, def)
+		return def
+	}
+	return d
+}
+
+// Decode unmarshals the param called name into v, for params that are lists or objects. v is left alone if it is not set
+func (p Params) Decode(name string, v interface{}) error {
+	value, ok := p[name]
+	if !ok {
+		return nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,259 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/kyokomi/emoji"
+	"github.com/weAutomateEverything/prognosisHalBot/prognosis"
+	"golang.org/x/net/context"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type environment struct {
+	Address  []string
+	Monitors []*monitors
+	//DailySummary is the time of day, like 17:00, the state of the switch is sent to every group. Empty turns it off
+	DailySummary string
+	//Correlations group the failures of related monitors into one parent incident
+	Correlations []correlation
+}
+
+type monitors struct {
+	Type, Dashboard, Id, Name, ObjectType string
+	Group                                 int64
+	//Interval is a duration, like 30s or 5m. Schedule is a time window, like Mon-Fri 06:00-22:00, or a cron expression
+	Interval, Schedule string
+	//Notifier is hal, webhook, smtp or log. Target is where the notifier sends to, and defaults to the Group
+	Notifier, Target string
+	//Mode is live, shadow or disabled. Defaults to live
+	Mode string
+	//Escalation is the ordered list of steps taken while a failure lasts. Defaults to defaultEscalation
+	Escalation []escalationStep
+	//ReminderBackoff is what the wait between repeats of a step is multiplied by each time. Defaults to 2
+	ReminderBackoff float64
+	//Sites are the dashboards a cross site monitor compares, instead of Dashboard and Id
+	Sites []site
+	//Params are passed on to the Monitor implementation, for the thresholds it lets each monitor tune
+	Params Params
+}
+
+type site struct {
+	Name, Dashboard, Id string
+}
+
+// widgets are the dashboard widgets the monitor reads. That is its Sites, or its own Dashboard and Id
+func (m *monitors) widgets() []site {
+	if len(m.Sites) > 0 {
+		return m.Sites
+	}
+	return []site{{Name: m.Name, Dashboard: m.Dashboard, Id: m.Id}}
+}
+
+const (
+	modeLive     = "live"
+	modeShadow   = "shadow"
+	modeDisabled = "disabled"
+)
+
+func (m *monitors) shadow() bool {
+	return m.Mode == modeShadow
+}
+
+func (m *monitors) disabled() bool {
+	return m.Mode == modeDisabled
+}
+
+// ConfigChanges lists the names of the monitors that were changed by a config reload
+type ConfigChanges struct {
+	Added, Removed, Updated []string
+	AddressChanged          bool
+	DailySummaryChanged     bool
+	CorrelationsChanged     bool
+}
+
+func (c ConfigChanges) empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Updated) == 0 && !c.AddressChanged && !c.DailySummaryChanged && !c.CorrelationsChanged
+}
+
+func (c ConfigChanges) String() string {
+	msg := emoji.Sprint(":arrows_counterclockwise: Prognosis config reloaded.")
+	if c.AddressChanged {
+		msg += "\nThe Prognosis addresses changed."
+	}
+	if c.DailySummaryChanged {
+		msg += "\nThe daily summary time changed."
+	}
+	if c.CorrelationsChanged {
+		msg += "\nThe correlations changed."
+	}
+	if len(c.Added) > 0 {
+		msg += "\n*Added:* " + strings.Join(c.Added, ", ")
+	}
+	if len(c.Removed) > 0 {
+		msg += "\n*Removed:* " + strings.Join(c.Removed, ", ")
+	}
+	if len(c.Updated) > 0 {
+		msg += "\n*Updated:* " + strings.Join(c.Updated, ", ")
+	}
+	return msg
+}
+
+/*
+ReloadConfig downloads CONFIG_URL again and swaps the running monitors for the new ones. Monitors that did not change
+keep running on their current schedule. Failure counts are stored by monitor name, so they carry over to updated
+monitors as well. If the new config can not be downloaded or is invalid, we keep running the current one.
+*/
+func (s *service) ReloadConfig(ctx context.Context) (changes ConfigChanges, err error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	defer func() {
+		if err != nil {
+			log.Printf("Unable to reload config: %v", err)
+			s.sendMessage(ctx, fmt.Sprintf("Unable to reload the config, still running the previous one. %v", err.Error()), getErrorGroup())
+		}
+	}()
+
+	c, err := downloadConfig()
+	if err != nil {
+		return
+	}
+	err = s.validateConfig(c)
+	if err != nil {
+		return
+	}
+
+	current := s.configuration()
+	changes = diffConfig(current, c)
+	if changes.empty() {
+		log.Println("Config reloaded, nothing changed")
+		return
+	}
+
+	//Keep the running instance of the monitors that did not change, so the scheduler leaves them alone
+	running := map[string]*monitors{}
+	for _, m := range current.Monitors {
+		running[m.Name] = m
+	}
+	for i, m := range c.Monitors {
+		if old, ok := running[m.Name]; ok && sameMonitor(old, m) {
+			c.Monitors[i] = old
+		}
+	}
+
+	if changes.AddressChanged {
+		err = s.setAddresses(ctx, c.Address)
+		if err != nil {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	s.config = c
+	s.mu.Unlock()
+	s.pruneParents(ctx)
+	s.resolveRemoved(ctx, changes.Removed)
+
+	//The scheduler only needs the latest monitors, so replace any it has not picked up yet instead of waiting for it
+	select {
+	case <-s.reload:
+	default:
+	}
+	s.reload <- c.Monitors
+
+	log.Println(changes.String())
+	s.sendMessage(ctx, changes.String(), getErrorGroup())
+	return
+}
+
+func (s *service) reloadConfigPeriodically(ctx context.Context) {
+	interval := envDuration("CONFIG_RELOAD_INTERVAL", 15*time.Minute)
+	if interval == 0 {
+		return
+	}
+	for {
+		time.Sleep(interval)
+		s.ReloadConfig(ctx)
+	}
+}
+
+func (s *service) configuration() environment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
+}
+
+/*
+setAddresses logs in to the new Prognosis hosts before they replace the current ones, trying them in order until one
+accepts the login. If none of them do, the new addresses are rejected and we stay on the hosts we have.
+*/
+func (s *service) setAddresses(ctx context.Context, addresses []string) error {
+	var clients []prognosis.Client
+	var hosts []hostHealth
+	var failures []string
+	current := -1
+	for i, address := range addresses {
+		c := prognosis.NewClient(address, getUsername(), getPassword())
+		h := hostHealth{healthy: true}
+		if current == -1 {
+			if err := c.Login(ctx); err != nil {
+				h = hostHealth{lastFailure: time.Now(), reason: err.Error()}
+				failures = append(failures, fmt.Sprintf("%v: %v", address, err))
+			} else {
+				current = i
+			}
+		}
+		clients = append(clients, c)
+		hosts = append(hosts, h)
+	}
+	if current == -1 {
+		return fmt.Errorf("unable to log in to any of the new Prognosis addresses. %v", strings.Join(failures, ", "))
+	}
+
+	s.failoverMu.Lock()
+	defer s.failoverMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients = clients
+	s.hosts = hosts
+	s.currentEnv = current
+	s.missing = map[string]bool{}
+	s.fetched = map[string]site{}
+	return nil
+}
+
+func diffConfig(old, new environment) (changes ConfigChanges) {
+	changes.AddressChanged = !reflect.DeepEqual(old.Address, new.Address)
+	changes.DailySummaryChanged = old.DailySummary != new.DailySummary
+	changes.CorrelationsChanged = !reflect.DeepEqual(old.Correlations, new.Correlations)
+
+	previous := map[string]*monitors{}
+	for _, m := range old.Monitors {
+		previous[m.Name] = m
+	}
+
+	for _, m := range new.Monitors {
+		p, ok := previous[m.Name]
+		if !ok {
+			changes.Added = append(changes.Added, m.Name)
+			continue
+		}
+		delete(previous, m.Name)
+		if !sameMonitor(p, m) {
+			changes.Updated = append(changes.Updated, m.Name)
+		}
+	}
+
+	for _, m := range old.Monitors {
+		if _, ok := previous[m.Name]; ok {
+			changes.Removed = append(changes.Removed, m.Name)
+		}
+	}
+	return
+}
+
+func sameMonitor(a, b *monitors) bool {
+	return reflect.DeepEqual(*a, *b)
+}
@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffConfig(t *testing.T) {
+	base := func() environment {
+		return environment{
+			Address: []string{"https://a", "https://b"},
+			Monitors: []*monitors{
+				{Name: "Rate", Type: "FailureRate", Dashboard: "D", Id: "1", ObjectType: "#", Group: 1},
+				{Name: "Codes", Type: "Code91", Dashboard: "D", Id: "2", ObjectType: "#", Group: 1},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *environment)
+		want   ConfigChanges
+	}{
+		{
+			name:   "nothing changed",
+			change: func(c *environment) {},
+		},
+		{
+			name:   "address changed",
+			change: func(c *environment) { c.Address = []string{"https://b", "https://a"} },
+			want:   ConfigChanges{AddressChanged: true},
+		},
+		{
+			name:   "monitor added",
+			change: func(c *environment) { c.Monitors = append(c.Monitors, &monitors{Name: "New"}) },
+			want:   ConfigChanges{Added: []string{"New"}},
+		},
+		{
+			name:   "monitor removed",
+			change: func(c *environment) { c.Monitors = c.Monitors[:1] },
+			want:   ConfigChanges{Removed: []string{"Codes"}},
+		},
+		{
+			name:   "monitor updated",
+			change: func(c *environment) { c.Monitors[1].Interval = "5m" },
+			want:   ConfigChanges{Updated: []string{"Codes"}},
+		},
+		{
+			name:   "params updated",
+			change: func(c *environment) { c.Monitors[0].Params = Params{"rows": float64(5)} },
+			want:   ConfigChanges{Updated: []string{"Rate"}},
+		},
+		{
+			name:   "monitors reordered",
+			change: func(c *environment) { c.Monitors[0], c.Monitors[1] = c.Monitors[1], c.Monitors[0] },
+		},
+		{
+			name:   "daily summary changed",
+			change: func(c *environment) { c.DailySummary = "17:00" },
+			want:   ConfigChanges{DailySummaryChanged: true},
+		},
+		{
+			name: "correlation added",
+			change: func(c *environment) {
+				c.Correlations = []correlation{{Name: "Switch", Monitors: []string{"Rate", "Codes"}}}
+			},
+			want: ConfigChanges{CorrelationsChanged: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, new := base(), base()
+			tt.change(&new)
+			got := diffConfig(old, new)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffConfig() = %+v, want %+v", got, tt.want)
+			}
+			if got.empty() != reflect.DeepEqual(tt.want, ConfigChanges{}) {
+				t.Errorf("empty() = %v", got.empty())
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package monitor
+
+import (
+	"fmt"
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+correlation groups the failures of related monitors into one parent incident, so one root cause does not page the group
+once for every monitor it sets off. A failure of one of the Monitors opens the parent, and failures of the others that
+start within Window (5m by default) join it as symptoms. The symptoms still alert, but only the parent escalates to a
+callout, using its own Escalation. The parent goes to the Group, Notifier and Target of the first monitor unless they are set.
+Shadow monitors stay out of the parent, so a monitor that is being tried out never pages through it.
+*/
+type correlation struct {
+	Name             string
+	Monitors         []string
+	Window           string
+	Group            int64
+	Notifier, Target string
+	Escalation       []escalationStep
+}
+
+func (c correlation) window() time.Duration {
+	d, err := time.ParseDuration(c.Window)
+	if err != nil || c.Window == "" {
+		return 5 * time.Minute
+	}
+	return d
+}
+
+// parentIncident is a correlation that has at least one monitor failing. symptoms holds the failure message of each symptom
+type parentIncident struct {
+	monitor  *monitors
+	start    time.Time
+	symptoms map[incidentSymptom]string
+}
+
+func (p *parentIncident) message() string {
+	var lines []string
+	for s, message := range p.symptoms {
+		lines = append(lines, fmt.Sprintf("• %v: %v", s, message))
+	}
+	sort.Strings(lines)
+	return fmt.Sprintf("%v, %v related failures\n%v", p.monitor.Name, len(lines), strings.Join(lines, "\n"))
+}
+
+// symptomList is the symptoms in the order of their monitor and key
+func (p *parentIncident) symptomList() (symptoms []incidentSymptom) {
+	for s := range p.symptoms {
+		symptoms = append(symptoms, s)
+	}
+	sort.Slice(symptoms, func(i, j int) bool {
+		if symptoms[i].Monitor != symptoms[j].Monitor {
+			return symptoms[i].Monitor < symptoms[j].Monitor
+		}
+		return symptoms[i].Key < symptoms[j].Key
+	})
+	return
+}
+
+/*
+parentMonitor is the monitor the parent incident escalates as. It takes what is not set on the rule from the first
+monitor, but is always live, as only live monitors join it.
+*/
+func (c correlation) parentMonitor(configs []*monitors) *monitors {
+	m := &monitors{Type: "Correlation", Name: c.Name, Group: c.Group, Notifier: c.Notifier, Target: c.Target, Escalation: c.Escalation}
+	for _, child := range configs {
+		if child.Name != c.Monitors[0] {
+			continue
+		}
+		if m.Group == 0 {
+			m.Group = child.Group
+		}
+		if m.Notifier == "" && m.Target == "" {
+			m.Notifier, m.Target = child.Notifier, child.Target
+		}
+	}
+	return m
+}
+
+// live is whether the monitor is in the correlation and can join its parent, so is in the config and neither a shadow nor disabled
+func (c correlation) live(configs []*monitors, name string) bool {
+	for _, m := range configs {
+		if m.Name != name {
+			continue
+		}
+		for _, member := range c.Monitors {
+			if member == name {
+				return !m.shadow() && !m.disabled()
+			}
+		}
+	}
+	return false
+}
+
+/*
+correlate adds the failure to the parent incident of the correlation the monitor belongs to. It returns the parent, or
+nil if the monitor is not in a correlation, is a shadow or disabled, or the failure started too long after the parent to be part of it.
+*/
+func (s *service) correlate(monitor *monitors, response Response) (parent *monitors) {
+	config := s.configuration()
+	var rule *correlation
+	for i, c := range config.Correlations {
+		for _, name := range c.Monitors {
+			if name == monitor.Name {
+				rule = &config.Correlations[i]
+			}
+		}
+	}
+	if rule == nil || !rule.live(config.Monitors, monitor.Name) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	p, ok := s.parents[rule.Name]
+	if !ok {
+		p = &parentIncident{monitor: rule.parentMonitor(config.Monitors), start: now, symptoms: map[incidentSymptom]string{}}
+		s.parents[rule.Name] = p
+	}
+	symptom := incidentSymptom{Monitor: monitor.Name, Key: response.Key}
+	if _, ok := p.symptoms[symptom]; !ok && now.Sub(p.start) > rule.window() {
+		return
+	}
+	p.symptoms[symptom] = response.FailureMsg
+	return p.monitor
+}
+
+/*
+escalateParents runs the escalation of every open parent incident every parentInterval. A parent escalates once each
+time, however many of its monitors failed since the last time.
+*/
+func (s *service) escalateParents(ctx context.Context) {
+	for {
+		time.Sleep(parentInterval)
+		s.correlationMu.Lock()
+		type open struct {
+			monitor  *monitors
+			message  string
+			symptoms []incidentSymptom
+		}
+		var parents []open
+		s.mu.Lock()
+		for _, p := range s.parents {
+			parents = append(parents, open{monitor: p.monitor, message: p.message(), symptoms: p.symptomList()})
+		}
+		s.mu.Unlock()
+
+		for _, p := range parents {
+			s.handleFailed(ctx, p.monitor, Response{Failure: true, FailureMsg: p.message})
+			err := s.store.SetIncidentSymptoms(p.monitor.Name, "", p.symptoms)
+			if err != nil {
+				log.Printf("Unable to save the symptoms of %v: %v", p.monitor.Name, err)
+			}
+		}
+		s.correlationMu.Unlock()
+	}
+}
+
+const parentInterval = 30 * time.Second
+
+/*
+restoreParents rebuilds the parent incidents that were open when the bot stopped, from their incidents in Mongo, so
+they carry on escalating where they left off. The symptoms clear as their monitors pass again. A parent without any
+live symptoms left in the config is resolved.
+*/
+func (s *service) restoreParents(ctx context.Context) {
+	config := s.configuration()
+	for _, rule := range config.Correlations {
+		incident, err := s.store.GetOpenIncident(rule.Name, "")
+		if err != nil && err != mgo.ErrNotFound {
+			log.Printf("Unable to read the open incident of %v: %v", rule.Name, err)
+			continue
+		}
+		p := &parentIncident{monitor: rule.parentMonitor(config.Monitors), start: incident.Start, symptoms: map[incidentSymptom]string{}}
+		for _, symptom := range incident.Symptoms {
+			if rule.live(config.Monitors, symptom.Monitor) {
+				p.symptoms[symptom] = "failing since before the restart"
+			}
+		}
+		if len(p.symptoms) == 0 {
+			//Resolves the failure count and incident, if they were left open
+			s.resolveParents(ctx, []*monitors{p.monitor})
+			continue
+		}
+		log.Printf("Restored the parent incident of %v with %v", rule.Name, p.symptomList())
+		s.mu.Lock()
+		s.parents[rule.Name] = p
+		s.mu.Unlock()
+	}
+}
+
+// clearSymptom takes a key that is healthy again out of its parent incident, and resolves the parent once it has none left
+func (s *service) clearSymptom(ctx context.Context, monitor *monitors, key string) {
+	var resolved []*monitors
+	s.mu.Lock()
+	symptom := incidentSymptom{Monitor: monitor.Name, Key: key}
+	for name, p := range s.parents {
+		if _, ok := p.symptoms[symptom]; !ok {
+			continue
+		}
+		delete(p.symptoms, symptom)
+		if len(p.symptoms) == 0 {
+			delete(s.parents, name)
+			resolved = append(resolved, p.monitor)
+		}
+	}
+	s.mu.Unlock()
+	s.resolveParents(ctx, resolved)
+}
+
+/*
+pruneParents drops the symptoms of monitors that a config reload made a shadow, disabled or took out of the correlation,
+and resolves the parents that have none left.
+*/
+func (s *service) pruneParents(ctx context.Context) {
+	config := s.configuration()
+	var resolved []*monitors
+	s.mu.Lock()
+	for name, p := range s.parents {
+		var rule correlation
+		for _, c := range config.Correlations {
+			if c.Name == name {
+				rule = c
+			}
+		}
+		for symptom := range p.symptoms {
+			if !rule.live(config.Monitors, symptom.Monitor) {
+				delete(p.symptoms, symptom)
+			}
+		}
+		if len(p.symptoms) == 0 {
+			delete(s.parents, name)
+			resolved = append(resolved, p.monitor)
+		}
+	}
+	s.mu.Unlock()
+	s.resolveParents(ctx, resolved)
+}
+
+func (s *service) resolveParents(ctx context.Context, resolved []*monitors) {
+	for _, parent := range resolved {
+		s.correlationMu.Lock()
+		s.handleResponses(ctx, parent, []Response{{}})
+		s.correlationMu.Unlock()
+	}
+}
@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"golang.org/x/net/context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testCorrelations() environment {
+	return environment{
+		Monitors: []*monitors{
+			{Name: "Rate", Group: 1, Mode: modeShadow},
+			{Name: "Codes", Group: 2, Notifier: "callout", Target: "switch"},
+			{Name: "Latency", Group: 3},
+			{Name: "Old", Group: 4, Mode: modeDisabled},
+			{Name: "Other", Group: 5},
+		},
+		Correlations: []correlation{{Name: "Switch", Monitors: []string{"Codes", "Rate", "Latency", "Old"}}},
+	}
+}
+
+func TestCorrelate(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []string
+		parent   bool
+		symptoms []string
+	}{
+		{
+			name:     "live monitor opens the parent",
+			failures: []string{"Codes"},
+			parent:   true,
+			symptoms: []string{"Codes"},
+		},
+		{
+			name:     "live monitors join the parent",
+			failures: []string{"Codes", "Latency"},
+			parent:   true,
+			symptoms: []string{"Codes", "Latency"},
+		},
+		{
+			name:     "shadow monitor does not open the parent",
+			failures: []string{"Rate"},
+		},
+		{
+			name:     "disabled monitor does not open the parent",
+			failures: []string{"Old"},
+		},
+		{
+			name:     "shadow monitor does not join the parent",
+			failures: []string{"Latency", "Rate"},
+			symptoms: []string{"Latency"},
+		},
+		{
+			name:     "monitor outside the correlation",
+			failures: []string{"Other"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &service{config: testCorrelations(), parents: map[string]*parentIncident{}}
+			var parent *monitors
+			for _, name := range tt.failures {
+				for _, m := range s.config.Monitors {
+					if m.Name == name {
+						parent = s.correlate(m, Response{Failure: true, FailureMsg: "failed"})
+					}
+				}
+			}
+			if (parent != nil) != tt.parent {
+				t.Fatalf("correlate() = %+v, want a parent %v", parent, tt.parent)
+			}
+			var symptoms []string
+			if p, ok := s.parents["Switch"]; ok {
+				for _, symptom := range p.symptomList() {
+					symptoms = append(symptoms, symptom.Monitor)
+				}
+			}
+			if !reflect.DeepEqual(symptoms, tt.symptoms) {
+				t.Errorf("symptoms = %v, want %v", symptoms, tt.symptoms)
+			}
+		})
+	}
+}
+
+func TestCorrelateWindow(t *testing.T) {
+	s := &service{config: testCorrelations(), parents: map[string]*parentIncident{}}
+	s.correlate(s.config.Monitors[1], Response{Failure: true})
+	s.parents["Switch"].start = time.Now().Add(-10 * time.Minute)
+
+	if parent := s.correlate(s.config.Monitors[2], Response{Failure: true}); parent != nil {
+		t.Errorf("a failure after the window joined the parent")
+	}
+	if parent := s.correlate(s.config.Monitors[1], Response{Failure: true}); parent == nil {
+		t.Errorf("a symptom that is still failing left the parent")
+	}
+}
+
+func TestParentMonitor(t *testing.T) {
+	c := testCorrelations()
+	m := c.Correlations[0].parentMonitor(c.Monitors)
+	if m.Name != "Switch" || m.Group != 2 || m.Notifier != "callout" || m.Target != "switch" || m.shadow() {
+		t.Errorf("parentMonitor() = %+v", m)
+	}
+
+	c.Correlations[0].Group = 9
+	c.Correlations[0].Monitors[0] = "Rate"
+	m = c.Correlations[0].parentMonitor(c.Monitors)
+	if m.Group != 9 || m.shadow() {
+		t.Errorf("parentMonitor() of a shadow = %+v, want group 9 and live", m)
+	}
+}
+
+// openIncidentStore returns the same open incident for every monitor and key
+type openIncidentStore struct {
+	fakeStore
+	incident Incident
+}
+
+func (f *openIncidentStore) GetOpenIncident(id string, key string) (Incident, error) {
+	return f.incident, nil
+}
+
+func TestRestoreParents(t *testing.T) {
+	store := &openIncidentStore{incident: Incident{Monitor: "Switch", Symptoms: []incidentSymptom{
+		{Monitor: "Codes", Key: "91"}, {Monitor: "Rate"}, {Monitor: "Latency Check", Key: "05"},
+	}}}
+	s := &service{store: store, config: testCorrelations(), parents: map[string]*parentIncident{}}
+
+	s.restoreParents(context.Background())
+
+	p, ok := s.parents["Switch"]
+	if !ok {
+		t.Fatal("the parent was not restored")
+	}
+	if symptoms := p.symptomList(); !reflect.DeepEqual(symptoms, []incidentSymptom{{Monitor: "Codes", Key: "91"}}) {
+		t.Errorf("symptoms = %v, want only the live monitor", symptoms)
+	}
+}
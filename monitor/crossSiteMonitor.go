@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"math"
+	"strings"
+)
+
+/*
+crossSiteMonitor compares the FailureRate widgets of two or more sites, like RDC and SDC, to catch a failed split that
+neither site's own monitor would flag. Everything it finds goes into one alert.
+*/
+type crossSiteMonitor struct {
+}
+
+func NewCrossSiteMonitor() Monitor {
+	return &crossSiteMonitor{}
+}
+
+func (s crossSiteMonitor) GetName() string {
+	return "CrossSite"
+}
+
+func (s crossSiteMonitor) CheckResponse(ctx context.Context, check Check, input [][]string) (response []Response, err error) {
+	return nil, errors.New("CrossSite monitors need Sites to compare")
+}
+
+func (s crossSiteMonitor) ValidateParams(p Params) error {
+	for _, name := range []string{"minVolume", "silentVolume", "splitTolerance", "failureRateDifference"} {
+		if v, ok := p[name]; ok {
+			if f, ok := v.(float64); !ok || f < 0 {
+				return fmt.Errorf("%v %v is not a positive number", name, v)
+			}
+		}
+	}
+	var split map[string]float64
+	if err := p.Decode("split", &split); err != nil {
+		return fmt.Errorf("split is not the percentage of the traffic each site should get. %v", err)
+	}
+	return nil
+}
+
+/*
+CheckSites compares the sites on the newest row that all of them have got to. A site that has already started the next
+row is compared on the row the others are still on, so a rollover is not taken for a silent site or a broken split.
+Once there are at least minVolume transactions between the sites, it checks
+for a site with no more than silentVolume (default 0) transactions, a site whose share of the traffic is more than
+splitTolerance (default 20) percentage points from its split (an even split by default), and failure rates that are more
+than failureRateDifference (default 10) percentage points apart.
+*/
+func (s crossSiteMonitor) CheckSites(ctx context.Context, check Check, sites []Site) (response []Response, err error) {
+	rows := make([]map[string]data, len(sites))
+	var id string
+	for i, site := range sites {
+		rows[i] = map[string]data{}
+		newest := ""
+		for _, y := range site.Rows {
+			d := rows[i][y[0]]
+			parseRateRow(y, &d)
+			rows[i][y[0]] = d
+			if y[0] > newest {
+				newest = y[0]
+			}
+		}
+		if newest != "" && (id == "" || newest < id) {
+			id = newest
+		}
+	}
+
+	totals := make([]data, len(sites))
+	var volume int
+	for i := range sites {
+		totals[i] = rows[i][id]
+		volume += totals[i].volume()
+	}
+
+	check.Log.Printf("Cross site volumes %v", describeSites(sites, totals))
+	resp := Response{}
+	if volume == 0 || volume < check.Params.Int("minVolume", 0) {
+		return []Response{resp}, nil
+	}
+
+	var split map[string]float64
+	check.Params.Decode("split", &split)
+
+	var findings []string
+	var silent []string
+	for i, site := range sites {
+		if totals[i].volume() <= check.Params.Int("silentVolume", 0) {
+			silent = append(silent, site.Name)
+		}
+	}
+	if len(silent) > 0 {
+		findings = append(findings, fmt.Sprintf("%v has gone silent while the other sites take all %v transactions", strings.Join(silent, " and "), volume))
+	} else {
+		tolerance := check.Params.Float("splitTolerance", 20)
+		for i, site := range sites {
+			expected, ok := split[site.Name]
+			if !ok {
+				expected = 100 / float64(len(sites))
+			}
+			share := 100 * float64(totals[i].volume()) / float64(volume)
+			if math.Abs(share-expected) > tolerance {
+				findings = append(findings, fmt.Sprintf("%v has %.1f%% of the traffic, where %.1f%% is expected", site.Name, share, expected))
+			}
+		}
+
+		minRate, maxRate := math.MaxFloat64, 0.0
+		var low, high string
+		for i, site := range sites {
+			rate := 100 * float64(totals[i].failed) / float64(totals[i].volume())
+			if rate < minRate {
+				minRate, low = rate, site.Name
+			}
+			if rate > maxRate {
+				maxRate, high = rate, site.Name
+			}
+		}
+		if limit := check.Params.Float("failureRateDifference", 10); maxRate-minRate > limit {
+			findings = append(findings, fmt.Sprintf("%v has %.1f%% failures against %.1f%% at %v", high, maxRate, minRate, low))
+		}
+	}
+
+	if len(findings) > 0 {
+		resp.Failure = true
+		resp.FailureMsg = fmt.Sprintf("%v: %v. %v", check.Config.Name, strings.Join(findings, ", "), describeSites(sites, totals))
+	}
+	return []Response{resp}, nil
+}
+
+func describeSites(sites []Site, totals []data) string {
+	var parts []string
+	for i, site := range sites {
+		parts = append(parts, fmt.Sprintf("%v %v approved, %v declined, %v failed", site.Name, totals[i].approved, totals[i].declined, totals[i].failed))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,70 @@
+package monitor
+
+import (
+	"golang.org/x/net/context"
+	"testing"
+)
+
+func TestCrossSiteMonitor(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  Params
+		rdc     [][]string
+		sdc     [][]string
+		failure bool
+	}{
+		{
+			name: "even split",
+			rdc:  rateRows("10:00", 100, 0, 0),
+			sdc:  rateRows("10:00", 90, 0, 0),
+		},
+		{
+			name:    "silent site",
+			rdc:     rateRows("10:00", 100, 0, 0),
+			sdc:     rateRows("10:00", 0, 0, 0),
+			failure: true,
+		},
+		{
+			name:    "broken split",
+			rdc:     rateRows("10:00", 90, 0, 0),
+			sdc:     rateRows("10:00", 10, 0, 0),
+			failure: true,
+		},
+		{
+			name:    "failure rates apart",
+			rdc:     rateRows("10:00", 80, 0, 20),
+			sdc:     rateRows("10:00", 100, 0, 0),
+			failure: true,
+		},
+		{
+			//RDC has started the 10:05 row and SDC has not, so both are compared on 10:00
+			name: "one site rolled over",
+			rdc:  append(rateRows("10:00", 100, 0, 0), rateRows("10:05", 1, 0, 0)...),
+			sdc:  rateRows("10:00", 95, 0, 0),
+		},
+		{
+			name:    "silent once both sites rolled over",
+			rdc:     append(rateRows("10:00", 100, 0, 0), rateRows("10:05", 50, 0, 0)...),
+			sdc:     append(rateRows("10:00", 95, 0, 0), rateRows("10:05", 0, 0, 0)...),
+			failure: true,
+		},
+		{
+			name:   "under the minimum volume",
+			params: Params{"minVolume": float64(500)},
+			rdc:    rateRows("10:00", 100, 0, 0),
+			sdc:    rateRows("10:00", 0, 0, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sites := []Site{{Name: "RDC", Rows: tt.rdc}, {Name: "SDC", Rows: tt.sdc}}
+			response, err := crossSiteMonitor{}.CheckSites(context.Background(), testCheck(tt.params, &fakeHistory{}), sites)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response[0].Failure != tt.failure {
+				t.Errorf("failure = %v, want %v. %v", response[0].Failure, tt.failure, response[0].FailureMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	"io"
+	"time"
+)
+
+func makeReloadConfigEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		return s.ReloadConfig(ctx)
+	}
+}
+
+func makeAddMaintenanceWindowEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		return s.AddMaintenanceWindow(ctx, request.(MaintenanceWindow))
+	}
+}
+
+func makeListMaintenanceWindowsEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		return s.MaintenanceWindows(ctx)
+	}
+}
+
+func makeDeleteMaintenanceWindowEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		err = s.DeleteMaintenanceWindow(ctx, request.(string))
+		return
+	}
+}
+
+type importCalendarRequest struct {
+	calendar        string
+	ics             io.Reader
+	monitors, nodes []string
+}
+
+func makeImportCalendarEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(importCalendarRequest)
+		return s.ImportCalendar(ctx, req.calendar, req.ics, req.monitors, req.nodes)
+	}
+}
+
+type incidentsRequest struct {
+	filter IncidentFilter
+	format string
+}
+
+type incidentsResponse struct {
+	format    string
+	incidents []Incident
+}
+
+func makeIncidentsEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(incidentsRequest)
+		incidents, err := s.Incidents(ctx, req.filter)
+		if err != nil {
+			return
+		}
+		return incidentsResponse{format: req.format, incidents: incidents}, nil
+	}
+}
+
+type availabilityRequest struct {
+	period, monitor string
+}
+
+func makeAvailabilityEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(availabilityRequest)
+		return s.Availability(ctx, req.period, req.monitor)
+	}
+}
+
+type historyRequest struct {
+	monitor  string
+	from, to time.Time
+}
+
+func makeHistoryEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(historyRequest)
+		return s.History(ctx, req.monitor, req.from, req.to)
+	}
+}
@@ -0,0 +1,167 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/kyokomi/emoji"
+	"golang.org/x/net/context"
+	"log"
+	"math"
+	"time"
+)
+
+const (
+	actionAlert   = "alert"
+	actionCallout = "callout"
+)
+
+/*
+escalationStep is one step of a monitors escalation policy. Once a failure has been going on for Delay, the step
+sends an alert or invokes a callout, to the monitors Group or the Group of the step. If it has a Repeat interval it
+fires again while the failure lasts, with the wait between repeats growing by the monitors ReminderBackoff every time.
+Otherwise it only fires once.
+*/
+type escalationStep struct {
+	Delay  string
+	Action string
+	Group  int64
+	Repeat string
+}
+
+func (e escalationStep) delay() time.Duration {
+	d, _ := time.ParseDuration(e.Delay)
+	return d
+}
+
+func (e escalationStep) repeat() time.Duration {
+	d, _ := time.ParseDuration(e.Repeat)
+	return d
+}
+
+/*
+defaultEscalation is used by monitors without a policy. It alerts once the failure is 30s old, sends reminders every
+REMINDER_INTERVAL after that, and calls out after 3m.
+*/
+func defaultEscalation() []escalationStep {
+	return []escalationStep{
+		{Delay: "30s", Action: actionAlert, Repeat: envDuration("REMINDER_INTERVAL", 15*time.Minute).String()},
+		{Delay: "3m", Action: actionCallout},
+	}
+}
+
+func (m *monitors) escalation() []escalationStep {
+	if len(m.Escalation) == 0 {
+		return defaultEscalation()
+	}
+	return m.Escalation
+}
+
+func (m *monitors) reminderBackoff() float64 {
+	if m.ReminderBackoff < 1 {
+		return 2
+	}
+	return m.ReminderBackoff
+}
+
+// due checks if the step should fire again, given how many times it has fired before
+func (e escalationStep) due(monitor *monitors, state escalationState) bool {
+	if state.Count == 0 {
+		return true
+	}
+	if e.repeat() == 0 {
+		return false
+	}
+	wait := time.Duration(float64(e.repeat()) * math.Pow(monitor.reminderBackoff(), float64(state.Count-1)))
+	if max := envDuration("REMINDER_MAX_INTERVAL", 4*time.Hour); wait > max || wait < 0 {
+		wait = max
+	}
+	return time.Since(state.Fired) >= wait
+}
+
+func (s *service) handleFailed(ctx context.Context, monitor *monitors, response Response) {
+	err := s.store.IncreaseCount(monitor.Name, response.Key)
+	if err != nil {
+		log.Println(err)
+		s.sendMessage(ctx, err.Error(), getErrorGroup())
+		return
+	}
+	_, t, err := s.store.GetCount(monitor.Name, response.Key)
+	if err != nil {
+		log.Println(err)
+		s.sendMessage(ctx, err.Error(), getErrorGroup())
+		return
+	}
+	d := time.Since(t).Truncate(time.Second)
+
+	err = s.store.RecordIncidentFailure(monitor.Name, response.Key, t, response)
+	if err != nil {
+		log.Printf("Unable to record the incident for %v %v: %v", monitor.Name, response.Key, err)
+	}
+
+	//A silenced failure is still counted, so the escalation carries on from where it is when the silence ends
+	silenced, err := s.silences.IsSilenced(monitor.Name, response.Key)
+	if err != nil {
+		log.Printf("Unable to check if %v %v is silenced: %v", monitor.Name, response.Key, err)
+	}
+	if silenced {
+		log.Printf("%v %v is silenced, not escalating", monitor.Name, response.Key)
+		return
+	}
+	if s.inMaintenance(monitor, response) {
+		log.Printf("%v %v is in a maintenance window, not escalating", monitor.Name, response.Key)
+		return
+	}
+
+	//When the monitor is part of a correlation, only the parent incident calls out
+	parent := s.correlate(monitor, response)
+
+	fired, err := s.store.GetEscalationState(monitor.Name, response.Key)
+	if err != nil {
+		s.sendMessage(ctx, fmt.Sprintf("Error reading the escalation state of %v %v: %v", monitor.Name, response.Key, err.Error()), getErrorGroup())
+		return
+	}
+
+	for i, step := range monitor.escalation() {
+		if d < step.delay() {
+			continue
+		}
+		var state escalationState
+		if i < len(fired) {
+			state = fired[i]
+		}
+		if !step.due(monitor, state) {
+			continue
+		}
+
+		switch step.Action {
+		case actionAlert:
+			log.Printf("Sending warning for %v", monitor.Name)
+			msg := emoji.Sprintf(":x: %v. Error has been occurring for %v.", response.FailureMsg, d.String())
+			if state.Count > 0 {
+				msg = emoji.Sprintf(":hourglass: Still failing after %v. %v", d.String(), response.FailureMsg)
+			}
+			s.alertGroup(ctx, monitor, step.Group, msg)
+			s.store.SetMessageSent(monitor.Name, response.Key)
+			s.store.AddIncidentAlert(monitor.Name, response.Key, msg)
+
+		case actionCallout:
+			if response.Severity == SeverityWarning || parent != nil {
+				continue
+			}
+			log.Printf("Invoking callout for %v %v\n", monitor.Name, response.Key)
+			err = s.calloutGroup(ctx, monitor, step.Group, response.FailureMsg, fmt.Sprintf("Prognosis Issue Detected. %v", response.FailureMsg))
+			if err != nil {
+				continue
+			}
+			err = s.store.SetCalloutInvoked(monitor.Name, response.Key)
+			if err != nil {
+				s.sendMessage(ctx, fmt.Sprintf("Error setting callout invoked: %v", err.Error()), getErrorGroup())
+			}
+			s.store.SetIncidentCallout(monitor.Name, response.Key)
+		}
+
+		err = s.store.SetEscalationStepFired(monitor.Name, response.Key, i)
+		if err != nil {
+			s.sendMessage(ctx, fmt.Sprintf("Error saving the escalation state of %v %v: %v", monitor.Name, response.Key, err.Error()), getErrorGroup())
+		}
+	}
+}
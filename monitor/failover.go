@@ -0,0 +1,199 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/kyokomi/emoji"
+	"github.com/weAutomateEverything/prognosisHalBot/prognosis"
+	"golang.org/x/net/context"
+	"log"
+	"time"
+)
+
+type hostHealth struct {
+	healthy     bool
+	lastFailure time.Time
+	reason      string
+}
+
+/*
+failover marks the failed Prognosis host as unhealthy and logs into the next healthy host in the Address list. If
+none of the healthy hosts accept the login, the unhealthy ones are tried as well - it is better to be on a host that
+failed a while ago than on none at all.
+
+Monitors run concurrently, so more than one of them can detect the same broken host. Only the first one fails over,
+the others find that the host they failed on is no longer the current one and return. If no host accepts the login we
+stay on the failed one and return, rather than wait for one while holding up the monitor.
+*/
+func (s *service) failover(ctx context.Context, failed prognosis.Client, reason string) {
+	s.failoverMu.Lock()
+	defer s.failoverMu.Unlock()
+
+	if s.client() != failed {
+		return
+	}
+
+	from := s.currentIndex()
+	s.markUnhealthy(from, reason)
+	log.Printf("Failing over from %v: %v", failed.Address(), reason)
+
+	s.resetTechErrors()
+	if !s.loginNext(ctx, from, true) && !s.loginNext(ctx, from, false) {
+		//Stay where we are, the technical errors that follow will fail over again
+		s.sendMessage(ctx, fmt.Sprintf("Unable to fail over from %v, none of the Prognosis hosts accept a login. %v",
+			failed.Address(), reason), getErrorGroup())
+		return
+	}
+
+	if s.client() != failed {
+		s.sendMessage(ctx, emoji.Sprintf(":twisted_rightwards_arrows: Prognosis failover from %v to %v. %v",
+			failed.Address(), s.client().Address(), reason), getErrorGroup())
+	}
+}
+
+func (s *service) loginNext(ctx context.Context, from int, healthyOnly bool) bool {
+	clients := s.clientList()
+	for i := 1; i <= len(clients); i++ {
+		if ctx.Err() != nil {
+			return false
+		}
+		next := (from + i) % len(clients)
+		if healthyOnly && !s.isHealthy(next) {
+			continue
+		}
+		err := clients[next].Login(ctx)
+		if err != nil {
+			s.markUnhealthy(next, err.Error())
+			s.sendMessage(ctx, "prognosis error - "+err.Error(), getErrorGroup())
+			continue
+		}
+		s.setCurrent(next)
+		return true
+	}
+	return false
+}
+
+/*
+widgetMissing is called when a monitor's widget is not on the current host's dashboard. That is usually a mistake in
+the config, and another host will not have it either. Only once the widgets of most of the monitors are missing is it
+the host that is broken, and we fail over.
+*/
+func (s *service) widgetMissing(ctx context.Context, c prognosis.Client, name string, w site) {
+	s.mu.Lock()
+	if s.clients[s.currentEnv] != c {
+		s.mu.Unlock()
+		return
+	}
+	s.missing[name] = true
+	delete(s.fetched, name)
+	missing := len(s.missing)
+	s.mu.Unlock()
+
+	enabled := 0
+	for _, m := range s.configuration().Monitors {
+		if !m.disabled() {
+			enabled++
+		}
+	}
+	if missing*2 > enabled {
+		s.failover(ctx, c, fmt.Sprintf("the widgets of %v of the %v monitors are missing, the last was %v on dashboard %v",
+			missing, enabled, name, w.Dashboard))
+	}
+}
+
+func (s *service) widgetFound(name string, w site) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.missing, name)
+	s.fetched[name] = w
+}
+
+// probeWidget is a widget of a monitor that is still enabled, which was read successfully the last time it was checked
+func (s *service) probeWidget(config environment) (w site, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range config.Monitors {
+		if w, ok = s.fetched[m.Name]; ok && !m.disabled() {
+			return w, true
+		}
+	}
+	return site{}, false
+}
+
+func (s *service) clientList() []prognosis.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clients
+}
+
+func (s *service) currentIndex() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.currentEnv
+}
+
+func (s *service) setCurrent(i int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hosts[i].healthy = true
+	if s.currentEnv != i {
+		s.missing = map[string]bool{}
+		s.fetched = map[string]site{}
+	}
+	s.currentEnv = i
+}
+
+func (s *service) isHealthy(i int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hosts[i].healthy
+}
+
+func (s *service) markUnhealthy(i int, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hosts[i].healthy = false
+	s.hosts[i].lastFailure = time.Now()
+	s.hosts[i].reason = reason
+}
+
+func (s *service) failback(ctx context.Context) {
+	for {
+		time.Sleep(envDuration("FAILBACK_INTERVAL", 5*time.Minute))
+		s.probePrimary(ctx)
+	}
+}
+
+/*
+probePrimary checks if the first host in the Address list is usable again after a failover, by logging in and looking
+up the GUID of a widget that was read successfully on the current host. If it is, we fail back to it. A widget that is
+missing or was removed from the config would keep us off a working primary, so those are never probed.
+*/
+func (s *service) probePrimary(ctx context.Context) {
+	s.failoverMu.Lock()
+	defer s.failoverMu.Unlock()
+
+	from := s.currentIndex()
+	if from == 0 {
+		return
+	}
+	w, ok := s.probeWidget(s.configuration())
+	if !ok {
+		log.Println("Not probing the primary host, none of the monitors have read their widget yet")
+		return
+	}
+	clients := s.clientList()
+	primary := clients[0]
+	err := primary.Login(ctx)
+	if err == nil {
+		_, err = primary.ResolveWidgetGUID(ctx, w.Dashboard, w.Id)
+	}
+	if err != nil {
+		log.Printf("Primary host %v is still unavailable: %v", primary.Address(), err)
+		s.markUnhealthy(0, err.Error())
+		return
+	}
+
+	s.setCurrent(0)
+	s.sendMessage(ctx, emoji.Sprintf(":leftwards_arrow_with_hook: Prognosis failback from %v to %v.",
+		clients[from].Address(), primary.Address()), getErrorGroup())
+}
@@ -0,0 +1,21 @@
+package monitor
+
+import "testing"
+
+func TestProbeWidget(t *testing.T) {
+	config := environment{Monitors: []*monitors{
+		{Name: "Gone", Mode: modeDisabled}, {Name: "Unread"}, {Name: "Read"},
+	}}
+	s := &service{fetched: map[string]site{
+		"Removed": {Dashboard: "R"}, "Gone": {Dashboard: "G"}, "Read": {Dashboard: "D", Id: "1"},
+	}}
+	w, ok := s.probeWidget(config)
+	if !ok || w.Dashboard != "D" {
+		t.Errorf("probeWidget() = %+v, %v, want the widget of Read", w, ok)
+	}
+
+	s.fetched = map[string]site{"Removed": {Dashboard: "R"}}
+	if w, ok := s.probeWidget(config); ok {
+		t.Errorf("probeWidget() = %+v, want none of the monitors in the config", w)
+	}
+}
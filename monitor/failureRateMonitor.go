@@ -2,13 +2,16 @@ package monitor
 
 import (
 	"fmt"
+	"github.com/weAutomateEverything/prognosisHalBot/businessHours"
 	"golang.org/x/net/context"
-	"log"
+	"math"
 	"sort"
 	"strconv"
+	"time"
 )
 
 type failureRateMonitor struct {
+	quiet *quietSince
 }
 
 func (s failureRateMonitor) GetName() string {
@@ -16,10 +19,44 @@ func (s failureRateMonitor) GetName() string {
 }
 
 func NewFailureRateMonitor() Monitor {
-	return &failureRateMonitor{}
+	return &failureRateMonitor{quiet: &quietSince{since: map[string]time.Time{}}}
 }
 
-func (s failureRateMonitor) CheckResponse(ctx context.Context, input [][]string) (response []Response, err error) {
+func (s failureRateMonitor) ValidateParams(p Params) error {
+	if v, ok := p["businessHours"]; ok {
+		h, isString := v.(string)
+		if _, err := businessHours.Parse(h); !isString || err != nil {
+			return fmt.Errorf("businessHours %v is not business hours, like 07H00-19H00 or 24 X 7", v)
+		}
+	}
+	if v, ok := p["percentOf"]; ok && v != "approved" && v != "all" {
+		return fmt.Errorf("percentOf %v is not approved or all", v)
+	}
+	for _, name := range []string{"window", "trendWindow", "flatline"} {
+		if v, ok := p[name]; ok {
+			d, isString := v.(string)
+			if _, err := time.ParseDuration(d); !isString || err != nil {
+				return fmt.Errorf("%v %v is not a duration, like 10m", name, v)
+			}
+		}
+	}
+	for _, name := range []string{"rows", "minVolume", "failurePercent", "declinePercent", "approvalDropPercent", "baselineWeeks", "failureGrowth", "minFailures", "flatlineVolume"} {
+		if v, ok := p[name]; ok {
+			if f, ok := v.(float64); !ok || f < 0 {
+				return fmt.Errorf("%v %v is not a positive number", name, v)
+			}
+		}
+	}
+	return nil
+}
+
+/*
+CheckResponse works out the failed and declined transactions over the last rows rows (1 by default), or the transactions
+counted in the last window if it is set, as a percentage of the approved transactions. With percentOf set to all they are
+a percentage of all the transactions instead. Nothing is reported while there are fewer than minVolume transactions.
+Failures are checked against failurePercent (20 by default), and declines against declinePercent if it is set.
+*/
+func (s failureRateMonitor) CheckResponse(ctx context.Context, check Check, input [][]string) (response []Response, err error) {
 	result := map[string]data{}
 
 	for _, y := range input {
@@ -27,7 +64,7 @@ func (s failureRateMonitor) CheckResponse(ctx context.Context, input [][]string)
 		if !ok {
 			d = data{}
 		}
-		s.parseRow(y, &d)
+		parseRateRow(y, &d)
 		result[y[0]] = d
 	}
 
@@ -39,34 +76,95 @@ func (s failureRateMonitor) CheckResponse(ctx context.Context, input [][]string)
 
 	sort.Strings(keys)
 
-	lastKey := keys[len(keys)-1]
-
-	row := result[lastKey]
+	var rates []RateRecord
+	for _, key := range keys {
+		d := result[key]
+		rates = append(rates, RateRecord{Id: d.id, Approved: d.approved, Declined: d.declined, Failed: d.failed})
+	}
+	check.History.SaveRates(rates)
 
-	log.Printf("Rate Message - ID: %v, approved: %v, failed %v, declined: %v", row.id, row.approved, row.failed, row.declined)
+	//An empty input is a dashboard with only its header rows, which means there has been no traffic
+	newest := 0
+	if len(keys) > 0 {
+		newest = result[keys[len(keys)-1]].volume()
+	}
+	flatline := s.checkFlatline(check, newest, time.Now())
 
-	if row.approved == 0 {
-		if row.failed > 0 {
-			response = append(response, Response{
-				FailureMsg: fmt.Sprintf("No successful transactions found, only failed transactions (%v) ", row.failed),
-				Failure:    true,
-			})
+	//The trends are checked on the stored samples, so they still see the approvals stop when the dashboard is empty
+	trends, err := s.checkTrends(check, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	trends = append(trends, flatline...)
+
+	var total data
+	period := ""
+	if window := check.Params.Duration("window", 0); window > 0 {
+		period = "the last " + window.String()
+		total, err = rateTotal(check.History, time.Now().Add(-window), time.Now().Add(time.Second))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		rows := check.Params.Int("rows", 1)
+		if len(keys) == 0 {
+			return append([]Response{{}, {Key: "Declines"}}, trends...), nil
+		}
+		if rows < 1 || rows > len(keys) {
+			rows = len(keys)
+		}
+		period = fmt.Sprintf("the last %v rows", rows)
+		if rows == 1 {
+			period = "row " + keys[len(keys)-1]
 		}
-		return
+		for _, key := range keys[len(keys)-rows:] {
+			total.add(result[key])
+		}
+	}
+
+	volume := total.volume()
+	check.Log.Printf("Rate Message - %v, approved: %v, failed %v, declined: %v", period, total.approved, total.failed, total.declined)
+
+	failures := Response{}
+	declines := Response{Key: "Declines"}
+	if volume == 0 || volume < check.Params.Int("minVolume", 0) {
+		return append([]Response{failures, declines}, trends...), nil
+	}
+
+	base, of := total.approved, "approved"
+	if check.Params.String("percentOf", "approved") == "all" {
+		base, of = volume, "all"
+	}
+	failed, declined := percentOf(total.failed, base), percentOf(total.declined, base)
+	detail := fmt.Sprintf("%v failed and %v declined against %v approved, of %v transactions in %v",
+		total.failed, total.declined, total.approved, volume, period)
+	if base == 0 {
+		detail = "No successful transactions found. " + detail
+	}
+
+	if limit := check.Params.Float("failurePercent", 20); failed > limit {
+		failures.Failure = true
+		failures.FailureMsg = fmt.Sprintf("There is a high number of failed transactions, more than %v%% of %v. %v", limit, of, detail)
+	}
+	if limit := check.Params.Float("declinePercent", 0); limit > 0 && declined > limit {
+		declines.Failure = true
+		declines.FailureMsg = fmt.Sprintf("There is a high number of declined transactions, more than %v%% of %v. %v", limit, of, detail)
 	}
+	return append([]Response{failures, declines}, trends...), nil
+}
 
-	if row.failed/row.approved > 20/100 {
-		response = append(response, Response{
-			FailureMsg: fmt.Sprintf("There is a high number of failed transactions (%v) when compared to successful transactions (%v)", row.failed, row.approved),
-			Failure:    true,
-		})
-		return
+// percentOf is n as a percentage of base. Anything at all against a base of 0 is over every limit
+func percentOf(n, base int) float64 {
+	if base == 0 {
+		if n > 0 {
+			return math.Inf(1)
+		}
+		return 0
 	}
-	response = append(response, Response{})
-	return
+	return 100 * float64(n) / float64(base)
 }
 
-func (s *failureRateMonitor) parseRow(y []string, d *data) {
+func parseRateRow(y []string, d *data) {
 	d.id = y[0]
 	val, _ := strconv.Atoi(y[2])
 
@@ -86,3 +184,13 @@ type data struct {
 	id                         string
 	approved, declined, failed int
 }
+
+func (d data) volume() int {
+	return d.approved + d.declined + d.failed
+}
+
+func (d *data) add(o data) {
+	d.approved += o.approved
+	d.declined += o.declined
+	d.failed += o.failed
+}
@@ -0,0 +1,269 @@
+package monitor
+
+import (
+	"golang.org/x/net/context"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeHistory keeps the samples in memory. Saved samples are dated now, like the Mongo store does
+type fakeHistory struct {
+	rates       []RateRecord
+	codes       []ResponseCodeRecord
+	connections []ConnectionRecord
+}
+
+func (h *fakeHistory) SaveRates(rates []RateRecord) {
+	for _, r := range rates {
+		r.Date = time.Now()
+		h.rates = append(h.rates, r)
+	}
+}
+
+func (h *fakeHistory) SaveResponseCodes(codes []ResponseCodeRecord) {
+	for _, c := range codes {
+		c.Date = time.Now()
+		h.codes = append(h.codes, c)
+	}
+}
+
+func (h *fakeHistory) SaveConnections(connections []ConnectionRecord) {
+	for _, c := range connections {
+		c.Date = time.Now()
+		h.connections = append(h.connections, c)
+	}
+}
+
+func (h *fakeHistory) Rates(from, to time.Time) (result []RateRecord, err error) {
+	for _, r := range h.rates {
+		if !r.Date.Before(from) && r.Date.Before(to) {
+			result = append(result, r)
+		}
+	}
+	return
+}
+
+func (h *fakeHistory) ResponseCodes(from, to time.Time) (result []ResponseCodeRecord, err error) {
+	for _, c := range h.codes {
+		if !c.Date.Before(from) && c.Date.Before(to) {
+			result = append(result, c)
+		}
+	}
+	return
+}
+
+func (h *fakeHistory) Connections(from, to time.Time) (result []ConnectionRecord, err error) {
+	for _, c := range h.connections {
+		if !c.Date.Before(from) && c.Date.Before(to) {
+			result = append(result, c)
+		}
+	}
+	return
+}
+
+func testCheck(params Params, h History) Check {
+	return Check{
+		Config:  Config{Name: "Rate", Type: "FailureRate"},
+		Params:  params,
+		Log:     log.New(ioutil.Discard, "", 0),
+		History: h,
+	}
+}
+
+// rateRows are the widget rows of one row id, in the columns Prognosis returns them in
+func rateRows(id string, approved, declined, failed int) [][]string {
+	return [][]string{
+		{id, "", strconv.Itoa(approved), "Approved"},
+		{id, "", strconv.Itoa(declined), "Declined"},
+		{id, "", strconv.Itoa(failed), "Failed"},
+	}
+}
+
+func sample(ago time.Duration, id string, approved, declined, failed int) RateRecord {
+	return RateRecord{Date: time.Now().Add(-ago), Id: id, Approved: approved, Declined: declined, Failed: failed}
+}
+
+func TestFailureRateMonitor(t *testing.T) {
+	tests := []struct {
+		name               string
+		params             Params
+		history            []RateRecord
+		input              [][]string
+		failures, declines bool
+	}{
+		{
+			name:  "under the failure percent",
+			input: rateRows("10:00", 80, 10, 10),
+		},
+		{
+			name:     "over the failure percent",
+			input:    rateRows("10:00", 60, 10, 30),
+			failures: true,
+		},
+		{
+			name:   "own failure percent",
+			params: Params{"failurePercent": float64(60)},
+			input:  rateRows("10:00", 60, 10, 30),
+		},
+		{
+			name:     "percent of the approved transactions",
+			input:    rateRows("10:00", 90, 10, 20),
+			failures: true,
+		},
+		{
+			name:   "percent of all transactions",
+			params: Params{"percentOf": "all"},
+			input:  rateRows("10:00", 90, 10, 20),
+		},
+		{
+			name:     "no approved transactions",
+			params:   Params{"failurePercent": float64(90)},
+			input:    rateRows("10:00", 0, 0, 5),
+			failures: true,
+		},
+		{
+			name:   "under the minimum volume",
+			params: Params{"minVolume": float64(100)},
+			input:  rateRows("10:00", 5, 0, 5),
+		},
+		{
+			name:     "over the minimum volume",
+			params:   Params{"minVolume": float64(10)},
+			input:    rateRows("10:00", 5, 0, 5),
+			failures: true,
+		},
+		{
+			name:  "only the last row by default",
+			input: append(rateRows("09:55", 10, 0, 90), rateRows("10:00", 90, 0, 10)...),
+		},
+		{
+			name:     "the last rows",
+			params:   Params{"rows": float64(2)},
+			input:    append(rateRows("09:55", 10, 0, 90), rateRows("10:00", 90, 0, 10)...),
+			failures: true,
+		},
+		{
+			name:     "declines",
+			params:   Params{"declinePercent": float64(25)},
+			input:    rateRows("10:00", 60, 30, 10),
+			declines: true,
+		},
+		{
+			name:  "no traffic",
+			input: nil,
+		},
+		{
+			//Only what the row counted in the window is used. Its running total is 40 of 150, which is under 30%
+			name:   "window counts the growth of a row",
+			params: Params{"window": "10m", "failurePercent": float64(30)},
+			history: []RateRecord{
+				sample(20*time.Minute, "09:50", 100, 0, 0),
+				sample(5*time.Minute, "09:50", 150, 0, 40),
+			},
+			input:    rateRows("09:50", 150, 0, 40),
+			failures: true,
+		},
+		{
+			//Rows from before the window that are still on the dashboard, like yesterday's after midnight, count for nothing
+			name:   "window ignores rows that did not grow",
+			params: Params{"window": "10m"},
+			history: []RateRecord{
+				sample(20*time.Minute, "23:50", 10, 0, 90),
+				sample(5*time.Minute, "23:50", 10, 0, 90),
+			},
+			input: rateRows("23:50", 10, 0, 90),
+		},
+		{
+			name:   "window with a new row",
+			params: Params{"window": "10m", "minVolume": float64(50)},
+			history: []RateRecord{
+				sample(20*time.Minute, "09:50", 100, 0, 0),
+				sample(5*time.Minute, "09:50", 100, 0, 0),
+				sample(5*time.Minute, "10:00", 20, 0, 40),
+			},
+			input:    append(rateRows("09:50", 100, 0, 0), rateRows("10:00", 20, 0, 40)...),
+			failures: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &fakeHistory{rates: tt.history}
+			response, err := NewFailureRateMonitor().CheckResponse(context.Background(), testCheck(tt.params, h), tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response[0].Key != "" || response[1].Key != "Declines" {
+				t.Fatalf("unexpected keys %+v", response)
+			}
+			if response[0].Failure != tt.failures {
+				t.Errorf("failures = %v, want %v. %v", response[0].Failure, tt.failures, response[0].FailureMsg)
+			}
+			if response[1].Failure != tt.declines {
+				t.Errorf("declines = %v, want %v. %v", response[1].Failure, tt.declines, response[1].FailureMsg)
+			}
+		})
+	}
+}
+
+func TestFailureRateTrendsWithoutTraffic(t *testing.T) {
+	params := Params{"approvalDropPercent": float64(50)}
+	h := &fakeHistory{rates: []RateRecord{sample(7*24*time.Hour+5*time.Minute, "a", 100, 0, 0)}}
+	response, err := NewFailureRateMonitor().CheckResponse(context.Background(), testCheck(params, h), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response) != 3 || response[2].Key != "Approvals" || !response[2].Failure {
+		t.Errorf("CheckResponse() = %+v, want the approvals to have dropped", response)
+	}
+}
+
+func TestRateDelta(t *testing.T) {
+	from := time.Now().Add(-10 * time.Minute)
+	tests := []struct {
+		name    string
+		records []RateRecord
+		want    data
+	}{
+		{
+			name:    "first seen in the period",
+			records: []RateRecord{sample(5*time.Minute, "a", 10, 2, 1)},
+			want:    data{approved: 10, declined: 2, failed: 1},
+		},
+		{
+			name:    "seen before the period",
+			records: []RateRecord{sample(15*time.Minute, "a", 10, 2, 1), sample(5*time.Minute, "a", 15, 2, 4)},
+			want:    data{approved: 5, declined: 0, failed: 3},
+		},
+		{
+			name: "several samples in the period",
+			records: []RateRecord{sample(15*time.Minute, "a", 10, 0, 0), sample(8*time.Minute, "a", 12, 0, 0),
+				sample(2*time.Minute, "a", 20, 0, 1)},
+			want: data{approved: 10, failed: 1},
+		},
+		{
+			name:    "id reused by a new row",
+			records: []RateRecord{sample(15*time.Minute, "a", 100, 0, 0), sample(5*time.Minute, "a", 3, 0, 1)},
+			want:    data{approved: 3, failed: 1},
+		},
+		{
+			name:    "only before the period",
+			records: []RateRecord{sample(15*time.Minute, "a", 100, 0, 0)},
+		},
+		{
+			name:    "rows are added up",
+			records: []RateRecord{sample(5*time.Minute, "a", 1, 0, 0), sample(5*time.Minute, "b", 2, 0, 0)},
+			want:    data{approved: 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rateDelta(tt.records, from); got != tt.want {
+				t.Errorf("rateDelta() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
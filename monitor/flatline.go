@@ -0,0 +1,53 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/weAutomateEverything/prognosisHalBot/businessHours"
+	"sync"
+	"time"
+)
+
+// quietSince remembers when each monitor's volume went quiet, by monitor name
+type quietSince struct {
+	mu    sync.Mutex
+	since map[string]time.Time
+}
+
+/*
+checkFlatline alerts when the volume of the newest row has been at or below flatlineVolume (0 by default) for the
+flatline duration, during businessHours ("24 X 7" by default). A dashboard with only its header rows counts as no traffic.
+It is off unless flatline is set.
+*/
+func (s failureRateMonitor) checkFlatline(check Check, volume int, now time.Time) []Response {
+	limit := check.Params.Duration("flatline", 0)
+	if limit == 0 {
+		return nil
+	}
+	resp := Response{Key: "Flatline"}
+
+	hours, err := businessHours.Parse(check.Params.String("businessHours", "24 X 7"))
+	if err != nil {
+		check.Log.Println(err)
+		hours = businessHours.Always
+	}
+
+	s.quiet.mu.Lock()
+	defer s.quiet.mu.Unlock()
+	//The quiet night does not count, the flatline is timed from when business hours start
+	if volume > check.Params.Int("flatlineVolume", 0) || !hours.Contains(now) {
+		delete(s.quiet.since, check.Config.Name)
+		return []Response{resp}
+	}
+	since, ok := s.quiet.since[check.Config.Name]
+	if !ok {
+		since = now
+		s.quiet.since[check.Config.Name] = since
+	}
+
+	if now.Sub(since) >= limit {
+		resp.Failure = true
+		resp.FailureMsg = fmt.Sprintf("%v has had %v transactions since %v, during business hours %v. The switch may have stopped processing",
+			check.Config.Name, volume, since.Format("15:04"), hours)
+	}
+	return []Response{resp}
+}
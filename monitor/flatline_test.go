@@ -0,0 +1,81 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckFlatline(t *testing.T) {
+	day := time.Date(2024, 1, 8, 0, 0, 0, 0, time.Local)
+	at := func(clock string) time.Time {
+		minutes, err := parseClock(clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return day.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	type step struct {
+		clock   string
+		volume  int
+		failure bool
+	}
+	tests := []struct {
+		name   string
+		params Params
+		steps  []step
+	}{
+		{
+			name:   "quiet for longer than the flatline",
+			params: Params{"flatline": "15m"},
+			steps:  []step{{"10:00", 0, false}, {"10:10", 0, false}, {"10:15", 0, true}, {"10:20", 5, false}},
+		},
+		{
+			name:   "traffic under the flatline volume",
+			params: Params{"flatline": "15m", "flatlineVolume": float64(10)},
+			steps:  []step{{"10:00", 8, false}, {"10:15", 10, true}, {"10:20", 11, false}},
+		},
+		{
+			name:   "traffic starts the flatline again",
+			params: Params{"flatline": "15m"},
+			steps:  []step{{"10:00", 0, false}, {"10:10", 3, false}, {"10:20", 0, false}, {"10:30", 0, false}, {"10:35", 0, true}},
+		},
+		{
+			//The night was quiet, but the flatline is timed from 07:00
+			name:   "quiet night",
+			params: Params{"flatline": "15m", "businessHours": "07H00-19H00"},
+			steps:  []step{{"02:00", 0, false}, {"06:55", 0, false}, {"07:00", 0, false}, {"07:10", 0, false}, {"07:15", 0, true}},
+		},
+		{
+			name:   "evening",
+			params: Params{"flatline": "15m", "businessHours": "07H00-19H00"},
+			steps:  []step{{"18:50", 0, false}, {"19:05", 0, false}, {"19:30", 0, false}},
+		},
+		{
+			name:   "business hours past midnight",
+			params: Params{"flatline": "15m", "businessHours": "22H00-06H00"},
+			steps:  []step{{"21:50", 0, false}, {"22:00", 0, false}, {"22:15", 0, true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewFailureRateMonitor().(*failureRateMonitor)
+			check := testCheck(tt.params, &fakeHistory{})
+			for _, st := range tt.steps {
+				response := s.checkFlatline(check, st.volume, at(st.clock))
+				if len(response) != 1 || response[0].Key != "Flatline" {
+					t.Fatalf("checkFlatline() = %+v", response)
+				}
+				if response[0].Failure != st.failure {
+					t.Errorf("%v with %v transactions: failure = %v, want %v", st.clock, st.volume, response[0].Failure, st.failure)
+				}
+			}
+		})
+	}
+
+	s := NewFailureRateMonitor().(*failureRateMonitor)
+	if response := s.checkFlatline(testCheck(Params{}, &fakeHistory{}), 0, at("10:00")); response != nil {
+		t.Errorf("checkFlatline() without a flatline = %+v, want nothing", response)
+	}
+}
@@ -0,0 +1,180 @@
+package monitor
+
+import (
+	"fmt"
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2"
+	"log"
+	"time"
+)
+
+// History is the store of samples a Monitor implementation gets, scoped to the monitor being checked
+type History interface {
+	SaveRates(rates []RateRecord)
+	SaveResponseCodes(codes []ResponseCodeRecord)
+	Rates(from, to time.Time) ([]RateRecord, error)
+	ResponseCodes(from, to time.Time) ([]ResponseCodeRecord, error)
+	SaveConnections(connections []ConnectionRecord)
+	Connections(from, to time.Time) ([]ConnectionRecord, error)
+}
+
+type history struct {
+	store              Store
+	monitor, dashboard string
+}
+
+// SaveRates tags the rows with the monitor and stores them
+func (h history) SaveRates(rates []RateRecord) {
+	for _, r := range rates {
+		r.Monitor, r.Dashboard = h.monitor, h.dashboard
+		h.store.SaveRateData(r)
+	}
+}
+
+func (h history) SaveResponseCodes(codes []ResponseCodeRecord) {
+	for i := range codes {
+		codes[i].Monitor, codes[i].Dashboard = h.monitor, h.dashboard
+	}
+	h.store.SaveResponceCodeData(codes)
+}
+
+func (h history) SaveConnections(connections []ConnectionRecord) {
+	for i := range connections {
+		connections[i].Monitor, connections[i].Dashboard = h.monitor, h.dashboard
+	}
+	h.store.SaveConnectionData(connections)
+}
+
+func (h history) Rates(from, to time.Time) ([]RateRecord, error) {
+	return h.store.GetRateData(h.monitor, from, to)
+}
+
+func (h history) ResponseCodes(from, to time.Time) ([]ResponseCodeRecord, error) {
+	return h.store.GetResponseCodeData(h.monitor, from, to)
+}
+
+func (h history) Connections(from, to time.Time) ([]ConnectionRecord, error) {
+	return h.store.GetConnectionData(h.monitor, from, to)
+}
+
+// historyLookback is how far before a period the samples are read, to find where each row stood when the period started
+const historyLookback = time.Hour
+
+// rateTotal adds up the transactions between from and to, from the samples of the rows. See rateDelta
+func rateTotal(h History, from, to time.Time) (data, error) {
+	records, err := h.Rates(from.Add(-historyLookback), to)
+	if err != nil {
+		return data{}, err
+	}
+	return rateDelta(records, from), nil
+}
+
+/*
+rateDelta adds up what the rows counted from from onwards. Prognosis keeps counting on a row while its period is
+current, so the samples of a row are running totals. What a row counted in the period is the growth of its samples
+from the last one before from, or from nothing if it was first seen in the period. A sample lower than the one before
+it is a new row that reused the id, and counts in full. The records have to be in date order.
+*/
+func rateDelta(records []RateRecord, from time.Time) (total data) {
+	previous := map[string]data{}
+	for _, r := range records {
+		current := data{approved: r.Approved, declined: r.Declined, failed: r.Failed}
+		before := previous[r.Id]
+		previous[r.Id] = current
+		if r.Date.Before(from) {
+			continue
+		}
+		if current.volume() < before.volume() {
+			before = data{}
+		}
+		total.add(data{
+			approved: growth(before.approved, current.approved),
+			declined: growth(before.declined, current.declined),
+			failed:   growth(before.failed, current.failed),
+		})
+	}
+	return
+}
+
+// codeTotals adds up the count of each response code between from and to, the same way rateTotal adds up transactions
+func codeTotals(h History, from, to time.Time) (map[string]int, error) {
+	records, err := h.ResponseCodes(from.Add(-historyLookback), to)
+	if err != nil {
+		return nil, err
+	}
+	previous := map[string]int{}
+	totals := map[string]int{}
+	for _, r := range records {
+		row := r.Id + "/" + r.Code
+		before := previous[row]
+		previous[row] = r.Count
+		if r.Date.Before(from) {
+			continue
+		}
+		if r.Count < before {
+			before = 0
+		}
+		totals[r.Code] += r.Count - before
+	}
+	return totals, nil
+}
+
+func growth(before, after int) int {
+	if after < before {
+		return 0
+	}
+	return after - before
+}
+
+/*
+History returns the samples stored for a FailureRate, Code91 or SourceSink monitor between from and to. They are
+RateRecords, ResponseCodeRecords or ConnectionRecords, depending on the type of the monitor.
+*/
+func (s *service) History(ctx context.Context, monitorName string, from, to time.Time) (interface{}, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-24 * time.Hour)
+	}
+	for _, m := range s.configuration().Monitors {
+		if m.Name != monitorName {
+			continue
+		}
+		switch m.Type {
+		case "FailureRate":
+			return s.store.GetRateData(m.Name, from, to)
+		case "Code91":
+			return s.store.GetResponseCodeData(m.Name, from, to)
+		case "SourceSink":
+			return s.store.GetConnectionData(m.Name, from, to)
+		}
+		return nil, fmt.Errorf("%v monitors do not store any history", m.Type)
+	}
+	return nil, fmt.Errorf("there is no monitor called %v", monitorName)
+}
+
+// ensureRetention makes Mongo remove the samples once they are older than HISTORY_RETENTION
+func ensureRetention(db *mgo.Database) {
+	retention := envDuration("HISTORY_RETENTION", 30*24*time.Hour)
+	for _, collection := range []string{"ratedata", "responsecode", "connectiondata"} {
+		c := db.C(collection)
+		err := c.EnsureIndex(mgo.Index{Key: []string{"monitor", "date"}})
+		if err != nil {
+			log.Printf("Unable to index %v: %v", collection, err)
+		}
+		if retention == 0 {
+			c.DropIndexName("date_ttl")
+			continue
+		}
+		//Mongo does not let a TTL index be changed in place, so it is dropped first when the retention changes
+		index := mgo.Index{Key: []string{"date"}, Name: "date_ttl", ExpireAfter: retention}
+		if c.EnsureIndex(index) != nil {
+			c.DropIndexName("date_ttl")
+			err = c.EnsureIndex(index)
+			if err != nil {
+				log.Printf("Unable to set the retention of %v: %v", collection, err)
+			}
+		}
+	}
+}
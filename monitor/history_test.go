@@ -0,0 +1,33 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCodeTotals(t *testing.T) {
+	code := func(ago time.Duration, id, code string, count int) ResponseCodeRecord {
+		return ResponseCodeRecord{Date: time.Now().Add(-ago), Id: id, Code: code, Count: count}
+	}
+	h := &fakeHistory{codes: []ResponseCodeRecord{
+		//Yesterday's row, still on the dashboard after midnight
+		code(20*time.Minute, "23:50", "91", 40),
+		code(5*time.Minute, "23:50", "91", 40),
+		//A row that carried on counting
+		code(20*time.Minute, "00:00", "91", 2),
+		code(5*time.Minute, "00:00", "91", 7),
+		code(5*time.Minute, "00:00", "68", 3),
+		//A new row
+		code(2*time.Minute, "00:10", "91", 1),
+	}}
+
+	got, err := codeTotals(h, time.Now().Add(-10*time.Minute), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"91": 6, "68": 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("codeTotals() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,243 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var icalDays = map[string]string{
+	"SU": "Sun", "MO": "Mon", "TU": "Tue", "WE": "Wed", "TH": "Thu", "FR": "Fri", "SA": "Sat",
+}
+
+/*
+parseCalendar reads the VEVENTs from an iCal file into maintenance windows. Events that repeat daily or weekly become
+windows with a schedule, and events that do not repeat become once off windows. Other kinds of repeats are an error
+that names the event.
+*/
+func parseCalendar(r io.Reader) (windows []MaintenanceWindow, err error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return
+	}
+
+	var event map[string]icalProperty
+	for _, line := range lines {
+		p := parseProperty(line)
+		switch {
+		case p.name == "BEGIN" && p.value == "VEVENT":
+			event = map[string]icalProperty{}
+		case p.name == "END" && p.value == "VEVENT":
+			w, err := eventToWindow(event)
+			if err != nil {
+				return nil, err
+			}
+			windows = append(windows, w)
+			event = nil
+		case event != nil:
+			event[p.name] = p
+		}
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no events found in the calendar")
+	}
+	return
+}
+
+type icalProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// unfold joins the lines that iCal splits over more than one line, which start with a space or a tab
+func unfold(r io.Reader) (lines []string, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func parseProperty(line string) (p icalProperty) {
+	p.params = map[string]string{}
+	i := strings.Index(line, ":")
+	if i < 0 {
+		p.name = line
+		return
+	}
+	p.value = line[i+1:]
+	parts := strings.Split(line[:i], ";")
+	p.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			p.params[strings.ToUpper(kv[0])] = kv[1]
+		}
+	}
+	return
+}
+
+func parseICalTime(p icalProperty) (time.Time, error) {
+	loc := time.Local
+	if tz, ok := p.params["TZID"]; ok {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown time zone %v", tz)
+		}
+		loc = l
+	}
+	var t time.Time
+	var err error
+	switch {
+	case strings.HasSuffix(p.value, "Z"):
+		t, err = time.Parse("20060102T150405Z", p.value)
+	case len(p.value) == 8:
+		t, err = time.ParseInLocation("20060102", p.value, loc)
+	default:
+		t, err = time.ParseInLocation("20060102T150405", p.value, loc)
+	}
+	if err != nil {
+		return t, fmt.Errorf("%v is not an iCal time", p.value)
+	}
+	//Windows are checked against the local time of the bot
+	return t.In(time.Local), nil
+}
+
+/*
+eventToWindow turns the event into a maintenance window. A repeating event gets a schedule, bounded by its DTSTART and
+the end of its last occurrence when the rule has an UNTIL or COUNT. Rules that a schedule can not express, like every
+other week or the first Monday of the month, and events with EXDATE or RDATE exceptions are rejected, so the import
+fails instead of the window silently covering the wrong times.
+*/
+func eventToWindow(event map[string]icalProperty) (w MaintenanceWindow, err error) {
+	w.Name = event["SUMMARY"].value
+	if w.Name == "" {
+		w.Name = event["UID"].value
+	}
+
+	start, err := parseICalTime(event["DTSTART"])
+	if err != nil {
+		return w, fmt.Errorf("%v: %v", w.Name, err)
+	}
+	end, err := parseICalTime(event["DTEND"])
+	if err != nil {
+		return w, fmt.Errorf("%v: %v", w.Name, err)
+	}
+
+	rule, ok := event["RRULE"]
+	if !ok {
+		w.Start, w.End = &start, &end
+		return
+	}
+
+	for _, name := range []string{"EXDATE", "RDATE"} {
+		if _, ok := event[name]; ok {
+			return w, fmt.Errorf("%v: %v is not supported, split the event instead", w.Name, name)
+		}
+	}
+	if end.Sub(start) >= 24*time.Hour {
+		return w, fmt.Errorf("%v: repeating windows have to be shorter than a day", w.Name)
+	}
+
+	parts := map[string]string{}
+	for _, part := range strings.Split(rule.value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "FREQ", "UNTIL", "COUNT", "INTERVAL", "BYDAY", "WKST":
+			parts[kv[0]] = kv[1]
+		default:
+			return w, fmt.Errorf("%v: %v in the repeat rule is not supported", w.Name, kv[0])
+		}
+	}
+	if i, ok := parts["INTERVAL"]; ok && i != "1" {
+		return w, fmt.Errorf("%v: only repeats every day or every week are supported, not every %v", w.Name, i)
+	}
+
+	var days [7]bool
+	var names []string
+	if byDay, ok := parts["BYDAY"]; ok {
+		for _, day := range strings.Split(byDay, ",") {
+			d, ok := icalDays[day]
+			if !ok {
+				return w, fmt.Errorf("%v: BYDAY %v is not supported", w.Name, day)
+			}
+			days[weekdays[strings.ToLower(d)]] = true
+			names = append(names, d)
+		}
+	}
+
+	times := fmt.Sprintf("%v-%v", start.Format("15:04"), end.Format("15:04"))
+	switch parts["FREQ"] {
+	case "DAILY":
+		if len(names) == 0 {
+			for i := range days {
+				days[i] = true
+			}
+		}
+	case "WEEKLY":
+		if len(names) == 0 {
+			days[start.Weekday()] = true
+			names = []string{start.Format("Mon")}
+		}
+	default:
+		return w, fmt.Errorf("%v: only daily and weekly repeats are supported, not %v", w.Name, rule.value)
+	}
+	w.Schedule = times
+	if len(names) > 0 {
+		w.Schedule = strings.Join(names, ",") + " " + times
+	}
+	w.Start = &start
+
+	var count int
+	var until *time.Time
+	if c, ok := parts["COUNT"]; ok {
+		count, err = strconv.Atoi(c)
+		if err != nil || count < 1 {
+			return w, fmt.Errorf("%v: COUNT %v is not a number of occurrences", w.Name, c)
+		}
+	}
+	if u, ok := parts["UNTIL"]; ok {
+		if count > 0 {
+			return w, fmt.Errorf("%v: a repeat rule can not have both UNTIL and COUNT", w.Name)
+		}
+		t, err := parseICalTime(icalProperty{value: u, params: event["DTSTART"].params})
+		if err != nil {
+			return w, fmt.Errorf("%v: %v", w.Name, err)
+		}
+		until = &t
+	}
+	if count > 0 || until != nil {
+		last := lastOccurrence(start, days, count, until).Add(end.Sub(start))
+		w.End = &last
+	}
+	return
+}
+
+// lastOccurrence walks the days from the first occurrence, and returns the start of the last one the COUNT or UNTIL allows
+func lastOccurrence(first time.Time, days [7]bool, count int, until *time.Time) time.Time {
+	last := first
+	n := 0
+	for t := first; count == 0 || n < count; t = t.AddDate(0, 0, 1) {
+		if until != nil && t.After(*until) {
+			break
+		}
+		if !days[t.Weekday()] {
+			continue
+		}
+		last = t
+		n++
+	}
+	return last
+}
@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCalendar(t *testing.T) {
+	day := func(s string) *time.Time {
+		d, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &d
+	}
+
+	tests := []struct {
+		name  string
+		event string
+		want  MaintenanceWindow
+		err   string
+	}{
+		{
+			name:  "once off",
+			event: "SUMMARY:Patching\nDTSTART:20240106T010000\nDTEND:20240106T040000",
+			want:  MaintenanceWindow{Name: "Patching", Start: day("2024-01-06 01:00"), End: day("2024-01-06 04:00")},
+		},
+		{
+			name:  "weekly on the start day",
+			event: "SUMMARY:Patching\nDTSTART:20240107T010000\nDTEND:20240107T040000\nRRULE:FREQ=WEEKLY",
+			want:  MaintenanceWindow{Name: "Patching", Schedule: "Sun 01:00-04:00", Start: day("2024-01-07 01:00")},
+		},
+		{
+			name:  "weekly on a list of days",
+			event: "SUMMARY:Patching\nDTSTART:20240107T220000\nDTEND:20240108T020000\nRRULE:FREQ=WEEKLY;BYDAY=SA,SU;WKST=MO",
+			want:  MaintenanceWindow{Name: "Patching", Schedule: "Sat,Sun 22:00-02:00", Start: day("2024-01-07 22:00")},
+		},
+		{
+			name:  "daily until",
+			event: "SUMMARY:Batch\nDTSTART:20240101T230000\nDTEND:20240101T233000\nRRULE:FREQ=DAILY;UNTIL=20240105T230000",
+			want:  MaintenanceWindow{Name: "Batch", Schedule: "23:00-23:30", Start: day("2024-01-01 23:00"), End: day("2024-01-05 23:30")},
+		},
+		{
+			name:  "weekly count",
+			event: "SUMMARY:Patching\nDTSTART:20240106T010000\nDTEND:20240106T040000\nRRULE:FREQ=WEEKLY;BYDAY=SA,SU;COUNT=3",
+			want:  MaintenanceWindow{Name: "Patching", Schedule: "Sat,Sun 01:00-04:00", Start: day("2024-01-06 01:00"), End: day("2024-01-13 04:00")},
+		},
+		{
+			name:  "every other week",
+			event: "SUMMARY:Patching\nDTSTART:20240106T010000\nDTEND:20240106T040000\nRRULE:FREQ=WEEKLY;INTERVAL=2",
+			err:   "Patching: only repeats every day or every week are supported, not every 2",
+		},
+		{
+			name:  "exceptions",
+			event: "SUMMARY:Patching\nDTSTART:20240106T010000\nDTEND:20240106T040000\nRRULE:FREQ=WEEKLY\nEXDATE:20240113T010000",
+			err:   "Patching: EXDATE is not supported, split the event instead",
+		},
+		{
+			name:  "first monday of the month",
+			event: "SUMMARY:Patching\nDTSTART:20240101T010000\nDTEND:20240101T040000\nRRULE:FREQ=MONTHLY;BYDAY=1MO",
+			err:   "Patching: BYDAY 1MO is not supported",
+		},
+		{
+			name:  "monthly",
+			event: "SUMMARY:Patching\nDTSTART:20240101T010000\nDTEND:20240101T040000\nRRULE:FREQ=MONTHLY",
+			err:   "Patching: only daily and weekly repeats are supported, not FREQ=MONTHLY",
+		},
+		{
+			name:  "unsupported part",
+			event: "SUMMARY:Patching\nDTSTART:20240101T010000\nDTEND:20240101T040000\nRRULE:FREQ=DAILY;BYHOUR=1",
+			err:   "Patching: BYHOUR in the repeat rule is not supported",
+		},
+		{
+			name:  "longer than a day",
+			event: "SUMMARY:Freeze\nDTSTART:20240101T010000\nDTEND:20240103T010000\nRRULE:FREQ=WEEKLY",
+			err:   "Freeze: repeating windows have to be shorter than a day",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ics := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\n" + strings.Replace(tt.event, "\n", "\r\n", -1) + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+			windows, err := parseCalendar(strings.NewReader(ics))
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("parseCalendar() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := windows[0]
+			if got.Name != tt.want.Name || got.Schedule != tt.want.Schedule || !sameTime(got.Start, tt.want.Start) || !sameTime(got.End, tt.want.End) {
+				t.Errorf("parseCalendar() = %v %q %v %v, want %v %q %v %v", got.Name, got.Schedule, got.Start, got.End,
+					tt.want.Name, tt.want.Schedule, tt.want.Start, tt.want.End)
+			}
+			if err := got.validate(); err != nil {
+				t.Errorf("validate() = %v", err)
+			}
+		})
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestMaintenanceWindowActive(t *testing.T) {
+	at := func(s string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	start, end := at("2024-01-06 01:00"), at("2024-01-13 04:00")
+	w := MaintenanceWindow{Name: "Patching", Schedule: "Sat,Sun 01:00-04:00", Start: &start, End: &end}
+
+	tests := []struct {
+		t    string
+		want bool
+	}{
+		{"2023-12-30 02:00", false},
+		{"2024-01-06 02:00", true},
+		{"2024-01-07 03:59", true},
+		{"2024-01-07 04:00", false},
+		{"2024-01-08 02:00", false},
+		{"2024-01-13 02:00", true},
+		{"2024-01-14 02:00", false},
+	}
+	for _, tt := range tests {
+		if got := w.active(at(tt.t)); got != tt.want {
+			t.Errorf("active(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
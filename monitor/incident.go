@@ -0,0 +1,185 @@
+package monitor
+
+import (
+	"encoding/csv"
+	"fmt"
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2/bson"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Incident is one failure episode of a monitor key, from the first failed check until a check passes again. Unlike the
+failure count, it is kept after the failure clears, for post incident reviews.
+*/
+type Incident struct {
+	ID      bson.ObjectId `json:"id" bson:"_id"`
+	Monitor string        `json:"monitor"`
+	Key     string        `json:"key"`
+	Start   time.Time     `json:"start"`
+	//End is the last check that failed
+	End time.Time `json:"end"`
+	//PeakMessage is the message of the first failure with the worst severity. LastMessage is that of the last check that failed
+	PeakMessage  string          `json:"peakMessage"`
+	PeakSeverity int             `json:"-"`
+	LastMessage  string          `json:"lastMessage"`
+	Failures     int             `json:"failures"`
+	Alerts       []incidentAlert `json:"alerts"`
+	Callout      *time.Time      `json:"callout,omitempty"`
+	//Symptoms are the monitors and keys of the failures that make up the incident of a correlation
+	Symptoms []incidentSymptom `json:"symptoms,omitempty"`
+	Resolved *time.Time        `json:"resolved,omitempty"`
+}
+
+type incidentAlert struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+type incidentSymptom struct {
+	Monitor string `json:"monitor"`
+	Key     string `json:"key"`
+}
+
+func (s incidentSymptom) String() string {
+	return strings.TrimSpace(s.Monitor + " " + s.Key)
+}
+
+// IncidentFilter selects the incidents that overlap From and To. An empty Monitor or Key matches everything
+type IncidentFilter struct {
+	From, To     time.Time
+	Monitor, Key string
+}
+
+func (s *service) Incidents(ctx context.Context, filter IncidentFilter) ([]Incident, error) {
+	if filter.To.IsZero() {
+		filter.To = time.Now()
+	}
+	if filter.From.IsZero() {
+		filter.From = filter.To.AddDate(0, 0, -30)
+	}
+	return s.store.GetIncidents(filter)
+}
+
+func writeIncidentsCSV(w io.Writer, incidents []Incident) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"Monitor", "Key", "Start", "End", "Resolved", "Duration", "Failures", "Alerts", "Callout", "Peak Message", "Last Message"})
+	for _, i := range incidents {
+		duration := i.End.Sub(i.Start)
+		if i.Resolved != nil {
+			duration = i.Resolved.Sub(i.Start)
+		}
+		var alerts []string
+		for _, a := range i.Alerts {
+			alerts = append(alerts, a.Time.Format(time.RFC3339))
+		}
+		out.Write([]string{i.Monitor, i.Key, i.Start.Format(time.RFC3339), i.End.Format(time.RFC3339),
+			formatOptionalTime(i.Resolved), duration.Truncate(time.Second).String(), strconv.Itoa(i.Failures),
+			strings.Join(alerts, " "), formatOptionalTime(i.Callout), i.PeakMessage, i.LastMessage})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// parseFilterTime accepts a date, like 2024-01-31, or a RFC3339 time
+func parseFilterTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return t, fmt.Errorf("%v is not a date or RFC3339 time", v)
+	}
+	return t, nil
+}
+
+/*
+resolveUnreported resolves the open incidents of keys the check did not report, like a node that dropped off the widget
+or a trend that is no longer configured. Nothing would ever report them as passing again.
+*/
+func (s *service) resolveUnreported(ctx context.Context, monitor *monitors, response []Response) {
+	incidents, err := s.store.GetOpenIncidents(monitor.Name)
+	if err != nil {
+		log.Printf("Unable to read the open incidents of %v: %v", monitor.Name, err)
+		return
+	}
+	reported := map[string]bool{}
+	for _, r := range response {
+		reported[r.Key] = true
+	}
+	var gone []Response
+	for _, i := range incidents {
+		if reported[i.Key] {
+			continue
+		}
+		log.Printf("%v %v is no longer reported, resolving its incident", monitor.Name, i.Key)
+		err = s.store.ResolveIncident(monitor.Name, i.Key)
+		if err != nil {
+			log.Printf("Unable to resolve the incident for %v %v: %v", monitor.Name, i.Key, err)
+		}
+		gone = append(gone, Response{Key: i.Key})
+	}
+	s.handleResponses(ctx, monitor, gone)
+}
+
+// resolveRemoved resolves the open incidents and zeroes the failure counts of monitors a config reload removed
+func (s *service) resolveRemoved(ctx context.Context, names []string) {
+	for _, name := range names {
+		incidents, err := s.store.GetOpenIncidents(name)
+		if err != nil {
+			log.Printf("Unable to read the open incidents of %v: %v", name, err)
+			continue
+		}
+		for _, i := range incidents {
+			err = s.store.ResolveIncident(name, i.Key)
+			if err != nil {
+				log.Printf("Unable to resolve the incident for %v %v: %v", name, i.Key, err)
+			}
+			err = s.store.ZeroCount(name, i.Key)
+			if err != nil {
+				log.Printf("Unable to zero the failure count of %v %v: %v", name, i.Key, err)
+			}
+			s.clearSymptom(ctx, &monitors{Name: name}, i.Key)
+		}
+	}
+}
+
+/*
+migrateCode91Keys resolves the failures Code91 monitors left on the empty key. Before every code had its own key, all
+of them failed on the empty key, and nothing reports it any more. It runs once at startup.
+*/
+func (s *service) migrateCode91Keys(ctx context.Context) {
+	for _, m := range s.configuration().Monitors {
+		if m.Type != "Code91" {
+			continue
+		}
+		err := s.store.ResolveIncident(m.Name, "")
+		if err != nil {
+			log.Printf("Unable to resolve the old incident for %v: %v", m.Name, err)
+		}
+		count, _, err := s.store.GetCount(m.Name, "")
+		if err != nil || count == 0 {
+			continue
+		}
+		log.Printf("Clearing the old failure count of %v", m.Name)
+		err = s.store.ZeroCount(m.Name, "")
+		if err != nil {
+			log.Printf("Unable to zero the old failure count of %v: %v", m.Name, err)
+		}
+		s.clearSymptom(ctx, m, "")
+	}
+}
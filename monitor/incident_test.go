@@ -0,0 +1,103 @@
+package monitor
+
+import (
+	"golang.org/x/net/context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fakeStore keeps the failure counts and open incidents in memory. Anything else it is asked for panics
+type fakeStore struct {
+	Store
+	counts   map[string]int
+	open     map[string][]string
+	resolved []string
+}
+
+func (f *fakeStore) GetCount(id string, key string) (int, time.Time, error) {
+	return f.counts[id+key], time.Now(), nil
+}
+
+func (f *fakeStore) ZeroCount(id string, key string) error {
+	f.counts[id+key] = 0
+	return nil
+}
+
+func (f *fakeStore) IsMessageSent(id string, key string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeStore) GetOpenIncidents(id string) (result []Incident, err error) {
+	for _, key := range f.open[id] {
+		result = append(result, Incident{Monitor: id, Key: key})
+	}
+	return
+}
+
+func (f *fakeStore) ResolveIncident(id string, key string) error {
+	for i, k := range f.open[id] {
+		if k == key {
+			f.open[id] = append(f.open[id][:i], f.open[id][i+1:]...)
+			f.resolved = append(f.resolved, incidentSymptom{Monitor: id, Key: key}.String())
+			return nil
+		}
+	}
+	return nil
+}
+
+func TestResolveUnreported(t *testing.T) {
+	store := &fakeStore{
+		counts: map[string]int{"SourceSinkATM": 3, "SourceSinkPOS": 2},
+		open:   map[string][]string{"SourceSink": {"ATM", "POS"}, "Other": {"ATM"}},
+	}
+	s := &service{store: store, parents: map[string]*parentIncident{}}
+	m := &monitors{Name: "SourceSink"}
+
+	s.resolveUnreported(context.Background(), m, []Response{{Key: "ATM", Failure: true}, {Key: "EFT"}})
+
+	if !reflect.DeepEqual(store.resolved, []string{"SourceSink POS"}) {
+		t.Errorf("resolved %v, want only the POS node that was not reported", store.resolved)
+	}
+	if store.counts["SourceSinkPOS"] != 0 || store.counts["SourceSinkATM"] != 3 {
+		t.Errorf("counts = %v", store.counts)
+	}
+}
+
+func TestResolveRemoved(t *testing.T) {
+	store := &fakeStore{
+		counts: map[string]int{"Rate": 5, "RateDeclines": 1, "Codes91": 2},
+		open:   map[string][]string{"Rate": {"", "Declines"}, "Codes": {"91"}},
+	}
+	s := &service{store: store, parents: map[string]*parentIncident{}}
+
+	s.resolveRemoved(context.Background(), []string{"Rate"})
+
+	sort.Strings(store.resolved)
+	if !reflect.DeepEqual(store.resolved, []string{"Rate", "Rate Declines"}) {
+		t.Errorf("resolved %v", store.resolved)
+	}
+	if store.counts["Rate"] != 0 || store.counts["RateDeclines"] != 0 || store.counts["Codes91"] != 2 {
+		t.Errorf("counts = %v", store.counts)
+	}
+}
+
+func TestMigrateCode91Keys(t *testing.T) {
+	store := &fakeStore{
+		counts: map[string]int{"Codes": 4, "Codes91": 2, "Rate": 5},
+		open:   map[string][]string{"Codes": {"", "91"}, "Rate": {""}},
+	}
+	s := &service{store: store, parents: map[string]*parentIncident{}, config: environment{Monitors: []*monitors{
+		{Name: "Codes", Type: "Code91"}, {Name: "Rate", Type: "FailureRate"},
+	}}}
+
+	s.migrateCode91Keys(context.Background())
+
+	if !reflect.DeepEqual(store.resolved, []string{"Codes"}) {
+		t.Errorf("resolved %v, want only the empty Code91 key", store.resolved)
+	}
+	if store.counts["Codes"] != 0 || store.counts["Codes91"] != 2 || store.counts["Rate"] != 5 {
+		t.Errorf("counts = %v", store.counts)
+	}
+}
@@ -0,0 +1,260 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configSource reads the raw config from wherever it is kept
+type configSource interface {
+	Read() ([]byte, error)
+	String() string
+}
+
+func downloadConfig() (environment, error) {
+	cfg := os.Getenv("CONFIG_URL")
+	if cfg == "" {
+		return environment{}, errors.New("CONFIG_URL environment variable is not set")
+	}
+	return loadConfig(cfg)
+}
+
+/*
+loadConfig reads the config from a http or https url, a file:// url or a plain file path. The config can be JSON or
+YAML, and ${VARIABLES} in its values are replaced with their environment values once it is parsed, so things like group
+ids can come from the environment without their values ever being parsed as config. A variable that is not set is
+reported at the path it is used in.
+*/
+func loadConfig(location string) (configs environment, err error) {
+	source, err := newConfigSource(location)
+	if err != nil {
+		return
+	}
+
+	log.Printf("Loading config from %v", source)
+	b, err := source.Read()
+	if err != nil {
+		return
+	}
+
+	v, err := parseConfig(location, b)
+	if err != nil {
+		return
+	}
+
+	var errs ConfigErrors
+	v = expandEnv("$", v, reflect.TypeOf(configs), &errs)
+	if len(errs) > 0 {
+		return configs, errs
+	}
+
+	b, err = json.Marshal(v)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(b, &configs)
+	if e, ok := err.(*json.UnmarshalTypeError); ok {
+		path := arrayIndex.ReplaceAllString("$."+e.Field, "[$1]")
+		err = ConfigErrors{{Path: path, Message: fmt.Sprintf("expected a %v, found a %v", e.Type, e.Value)}}
+	}
+	if err != nil {
+		return
+	}
+
+	//Defaults are filled in here, so the running monitors are never written to once they are loaded
+	for _, m := range configs.Monitors {
+		if m != nil && m.ObjectType == "" {
+			m.ObjectType = "#"
+		}
+	}
+	return
+}
+
+var arrayIndex = regexp.MustCompile(`\.(\d+)`)
+
+var envVariable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// parseConfig decodes the JSON or YAML into maps and lists, so both formats are expanded, and reported on, the same way
+func parseConfig(location string, b []byte) (v interface{}, err error) {
+	if isYAML(location, b) {
+		err = yaml.Unmarshal(b, &v)
+		if err != nil {
+			return nil, ConfigErrors{{Path: "$", Message: err.Error()}}
+		}
+		return convertYAML(v), nil
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	err = d.Decode(&v)
+	if e, ok := err.(*json.SyntaxError); ok {
+		return nil, ConfigErrors{{Path: "$", Message: fmt.Sprintf("%v at offset %v", e.Error(), e.Offset)}}
+	}
+	return v, err
+}
+
+/*
+expandEnv replaces the ${VARIABLES} in the string values of the parsed config, and reports the ones that are not set.
+t is the type the value is decoded into, if it is known. A value that is only a variable, like ${CARDS_GROUP}, becomes
+a number or a bool when that is what the field holds.
+*/
+func expandEnv(path string, v interface{}, t reflect.Type, errs *ConfigErrors) interface{} {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch value := v.(type) {
+	case map[string]interface{}:
+		var keys []string
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value[key] = expandEnv(path+"."+key, value[key], fieldType(t, key), errs)
+		}
+	case []interface{}:
+		var elem reflect.Type
+		if t != nil && t.Kind() == reflect.Slice {
+			elem = t.Elem()
+		}
+		for i, item := range value {
+			value[i] = expandEnv(fmt.Sprintf("%v[%v]", path, i), item, elem, errs)
+		}
+	case string:
+		return expandString(path, value, t, errs)
+	}
+	return v
+}
+
+func expandString(path, s string, t reflect.Type, errs *ConfigErrors) interface{} {
+	expanded := envVariable.ReplaceAllStringFunc(s, func(m string) string {
+		name := envVariable.FindStringSubmatch(m)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			*errs = append(*errs, ConfigError{Path: path, Message: fmt.Sprintf("${%v} is not set", name)})
+			return m
+		}
+		return v
+	})
+	if t == nil || s == "" || envVariable.FindString(s) != s {
+		return expanded
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64,
+		reflect.Interface:
+		if _, err := strconv.ParseFloat(expanded, 64); err == nil {
+			return json.Number(expanded)
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(expanded); err == nil {
+			return b
+		}
+	}
+	return expanded
+}
+
+// fieldType is the type the key of a map or struct is decoded into. Struct fields are matched like encoding/json does
+func fieldType(t reflect.Type, key string) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem()
+	case reflect.Struct:
+		if f, ok := t.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, key) }); ok {
+			return f.Type
+		}
+	}
+	return nil
+}
+
+func newConfigSource(location string) (configSource, error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme == "" {
+		return fileSource(location), nil
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return httpSource(location), nil
+	case "file":
+		return fileSource(u.Host + u.Path), nil
+	}
+	return nil, fmt.Errorf("unsupported config location %v. Use a http, https or file url", location)
+}
+
+type httpSource string
+
+// configClient downloads the config. It gives up on a server that stops responding, rather than hold up a reload
+var configClient = &http.Client{Timeout: 30 * time.Second}
+
+func (s httpSource) Read() ([]byte, error) {
+	resp, err := configClient.Get(string(s))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v downloading %v", resp.StatusCode, s)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (s httpSource) String() string {
+	return string(s)
+}
+
+type fileSource string
+
+func (s fileSource) Read() ([]byte, error) {
+	return ioutil.ReadFile(string(s))
+}
+
+func (s fileSource) String() string {
+	return "file://" + string(s)
+}
+
+func isYAML(location string, b []byte) bool {
+	switch strings.ToLower(filepath.Ext(location)) {
+	case ".yaml", ".yml":
+		return true
+	case ".json":
+		return false
+	}
+	trimmed := strings.TrimSpace(string(b))
+	return !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[")
+}
+
+// convertYAML turns the map[interface{}]interface{} values yaml.v2 decodes into, into maps encoding/json accepts
+func convertYAML(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for key, value := range t {
+			m[fmt.Sprintf("%v", key)] = convertYAML(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range t {
+			t[i] = convertYAML(value)
+		}
+		return t
+	}
+	return v
+}
@@ -0,0 +1,107 @@
+package monitor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfigVariables(t *testing.T) {
+	os.Setenv("TEST_GROUP", "42")
+	os.Unsetenv("TEST_MISSING")
+
+	tests := []struct {
+		name, file, config string
+		want               error
+	}{
+		{
+			name:   "set",
+			file:   "config.yaml",
+			config: "# ${TEST_MISSING} in a comment\nMonitors:\n  - Name: A\n    Group: ${TEST_GROUP}\n    Target: pa$$word\n",
+		},
+		{
+			name:   "unset in yaml",
+			file:   "config.yaml",
+			config: "Monitors:\n  - Name: A\n    Group: ${TEST_MISSING}\n",
+			want:   ConfigErrors{{Path: "$.Monitors[0].Group", Message: "${TEST_MISSING} is not set"}},
+		},
+		{
+			name:   "unset in a json string",
+			file:   "config.json",
+			config: `{"Monitors": [{"Name": "A", "Target": "${TEST_MISSING}"}]}`,
+			want:   ConfigErrors{{Path: "$.Monitors[0].Target", Message: "${TEST_MISSING} is not set"}},
+		},
+		{
+			name:   "set in json",
+			file:   "config.json",
+			config: `{"Monitors": [{"Name": "A", "Group": "${TEST_GROUP}", "Target": "pa$$word"}]}`,
+		},
+		{
+			name:   "unset in a json number",
+			file:   "config.json",
+			config: `{"Monitors": [{"Name": "A", "Group": "${TEST_MISSING}"}]}`,
+			want:   ConfigErrors{{Path: "$.Monitors[0].Group", Message: "${TEST_MISSING} is not set"}},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, tt.file)
+			if err := ioutil.WriteFile(file, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			c, err := loadConfig(file)
+			if !reflect.DeepEqual(err, tt.want) {
+				t.Fatalf("loadConfig() error = %v, want %v", err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			m := c.Monitors[0]
+			if m.Group != 42 || m.Target != "pa$$word" || m.ObjectType != "#" {
+				t.Errorf("loadConfig() = %+v", m)
+			}
+		})
+	}
+}
+
+func TestLoadConfigVariableValues(t *testing.T) {
+	os.Setenv("TEST_TARGET", "a\"b', Mode: disabled\n    Group: 7\\")
+	os.Setenv("TEST_GROUP", "42")
+
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, file := range []string{"config.yaml", "config.json"} {
+		config := "Monitors:\n  - {Name: A, Target: 'x ${TEST_TARGET}', Params: {limit: '${TEST_GROUP}'}}\n"
+		if file == "config.json" {
+			config = `{"Monitors": [{"Name": "A", "Target": "x ${TEST_TARGET}", "Params": {"limit": "${TEST_GROUP}"}}]}`
+		}
+		path := filepath.Join(dir, file)
+		if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		c, err := loadConfig(path)
+		if err != nil {
+			t.Fatalf("%v: loadConfig() error = %v", file, err)
+		}
+		m := c.Monitors[0]
+		if m.Target != "x "+os.Getenv("TEST_TARGET") || m.Mode != "" || m.Group != 0 {
+			t.Errorf("%v: the variable was parsed as config %+v", file, m)
+		}
+		if m.Params.Float("limit", 0) != 42 {
+			t.Errorf("%v: params = %v", file, m.Params)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kyokomi/emoji"
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2/bson"
+	"io"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+MaintenanceWindow stops alerts and callouts while planned work is done. A window either recurs on a Schedule, like
+"Sun 01:00-04:00", or runs once from Start to End. A window with a Schedule can have a Start and End as well, which are
+the dates it recurs between. Monitors and Nodes are patterns like "Postilion*" for the monitor names
+and the keys (the SourceSink node names) the window applies to. A window without either applies to everything.
+*/
+type MaintenanceWindow struct {
+	ID       bson.ObjectId `json:"id" bson:"_id"`
+	Name     string        `json:"name"`
+	Schedule string        `json:"schedule,omitempty"`
+	Start    *time.Time    `json:"start,omitempty"`
+	End      *time.Time    `json:"end,omitempty"`
+	Monitors []string      `json:"monitors,omitempty"`
+	Nodes    []string      `json:"nodes,omitempty"`
+	//Calendar is the name of the iCal calendar the window was imported from
+	Calendar string `json:"calendar,omitempty"`
+}
+
+func (w MaintenanceWindow) validate() error {
+	if w.Name == "" {
+		return errors.New("a maintenance window needs a name")
+	}
+	if w.Schedule != "" {
+		_, err := parseWindow(strings.Fields(w.Schedule))
+		if err != nil {
+			return err
+		}
+	} else if w.Start == nil || w.End == nil {
+		return errors.New("a maintenance window needs a schedule, or a start and end")
+	}
+	if w.Start != nil && w.End != nil && !w.End.After(*w.Start) {
+		return errors.New("the end of a maintenance window has to be after the start")
+	}
+	return nil
+}
+
+func (w MaintenanceWindow) active(t time.Time) bool {
+	if w.Start != nil && t.Before(*w.Start) || w.End != nil && !t.Before(*w.End) {
+		return false
+	}
+	if w.Schedule != "" {
+		window, err := parseWindow(strings.Fields(w.Schedule))
+		return err == nil && window.contains(t)
+	}
+	return w.Start != nil && w.End != nil
+}
+
+func (w MaintenanceWindow) appliesTo(monitor, key string) bool {
+	return matchesAny(w.Monitors, monitor) && matchesAny(w.Nodes, key)
+}
+
+// matchesAny checks the value against the patterns. A node pattern also matches the keys made from it, like NODE-Connections
+func matchesAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern+"-*", value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// suppressedFailure is a failure that was not alerted on, because it happened during a maintenance window
+type suppressedFailure struct {
+	monitor *monitors
+	key     string
+	message string
+	//since is when the failure started, filled in when the window ends
+	since time.Time
+}
+
+func (s *service) AddMaintenanceWindow(ctx context.Context, w MaintenanceWindow) (MaintenanceWindow, error) {
+	err := w.validate()
+	if err != nil {
+		return w, err
+	}
+	w.ID = bson.NewObjectId()
+	err = s.store.AddMaintenanceWindows([]MaintenanceWindow{w})
+	if err != nil {
+		return w, err
+	}
+	return w, s.loadMaintenanceWindows()
+}
+
+func (s *service) MaintenanceWindows(ctx context.Context) ([]MaintenanceWindow, error) {
+	return s.store.GetMaintenanceWindows()
+}
+
+func (s *service) DeleteMaintenanceWindow(ctx context.Context, id string) error {
+	err := s.store.DeleteMaintenanceWindow(id)
+	if err != nil {
+		return err
+	}
+	return s.loadMaintenanceWindows()
+}
+
+/*
+ImportCalendar replaces the windows of the calendar with the events in the iCal file. The monitors and nodes apply to
+every event in it, so a team keeps one calendar for the things they look after.
+*/
+func (s *service) ImportCalendar(ctx context.Context, calendar string, ics io.Reader, monitorNames, nodes []string) ([]MaintenanceWindow, error) {
+	windows, err := parseCalendar(ics)
+	if err != nil {
+		return nil, err
+	}
+	for i := range windows {
+		windows[i].ID = bson.NewObjectId()
+		windows[i].Calendar = calendar
+		windows[i].Monitors = monitorNames
+		windows[i].Nodes = nodes
+		err = windows[i].validate()
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", windows[i].Name, err)
+		}
+	}
+	err = s.store.ReplaceCalendar(calendar, windows)
+	if err != nil {
+		return nil, err
+	}
+	return windows, s.loadMaintenanceWindows()
+}
+
+func (s *service) loadMaintenanceWindows() error {
+	windows, err := s.store.GetMaintenanceWindows()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.maintenance = windows
+	s.mu.Unlock()
+	return nil
+}
+
+/*
+inMaintenance checks if a maintenance window covers the failure. If one does, the failure is remembered so it can be
+included in the summary sent when the window ends.
+*/
+func (s *service) inMaintenance(monitor *monitors, response Response) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, w := range s.maintenance {
+		if !w.active(now) || !w.appliesTo(monitor.Name, response.Key) {
+			continue
+		}
+		id := w.ID.Hex()
+		if s.suppressed[id] == nil {
+			s.suppressed[id] = map[string]suppressedFailure{}
+		}
+		s.suppressed[id][monitor.Name+response.Key] = suppressedFailure{monitor: monitor, key: response.Key, message: response.FailureMsg}
+		return true
+	}
+	return false
+}
+
+// watchMaintenanceWindows reloads the windows every minute, and sends the catch up summary for the windows that ended
+func (s *service) watchMaintenanceWindows(ctx context.Context) {
+	active := map[string]MaintenanceWindow{}
+	for {
+		err := s.loadMaintenanceWindows()
+		if err != nil {
+			log.Printf("Unable to load the maintenance windows: %v", err)
+		}
+
+		s.mu.Lock()
+		now := time.Now()
+		current := map[string]MaintenanceWindow{}
+		for _, w := range s.maintenance {
+			if w.active(now) {
+				current[w.ID.Hex()] = w
+			}
+		}
+		var ended []MaintenanceWindow
+		var failures []map[string]suppressedFailure
+		for id, w := range active {
+			if _, ok := current[id]; !ok {
+				ended = append(ended, w)
+				failures = append(failures, s.suppressed[id])
+				delete(s.suppressed, id)
+			}
+		}
+		s.mu.Unlock()
+
+		for i, w := range ended {
+			log.Printf("Maintenance window %v has ended", w.Name)
+			s.catchUp(ctx, w, failures[i])
+		}
+		active = current
+		time.Sleep(time.Minute)
+	}
+}
+
+/*
+catchUp tells each monitor's group about the failures from the maintenance window that are still going on. The alert
+steps the catch up message stands in for are marked as fired, so the next check sends a reminder instead of alerting
+again.
+*/
+func (s *service) catchUp(ctx context.Context, w MaintenanceWindow, failures map[string]suppressedFailure) {
+	failing := map[*monitors][]suppressedFailure{}
+	for _, f := range failures {
+		count, t, err := s.store.GetCount(f.monitor.Name, f.key)
+		if err != nil || count == 0 {
+			continue
+		}
+		f.since = t
+		failing[f.monitor] = append(failing[f.monitor], f)
+	}
+
+	for monitor, list := range failing {
+		sort.Slice(list, func(i, j int) bool { return list[i].key < list[j].key })
+		msg := emoji.Sprintf(":wrench: Maintenance window %v has ended, and %v is still failing.", w.Name, monitor.Name)
+		for _, f := range list {
+			msg += "\n" + f.message
+		}
+		s.alert(ctx, monitor, msg)
+		for _, f := range list {
+			s.store.SetMessageSent(monitor.Name, f.key)
+			s.store.AddIncidentAlert(monitor.Name, f.key, msg)
+			for i, step := range monitor.escalation() {
+				if step.Action != actionAlert || time.Since(f.since) < step.delay() {
+					continue
+				}
+				err := s.store.SetEscalationStepFired(monitor.Name, f.key, i)
+				if err != nil {
+					log.Printf("Unable to save the escalation state of %v %v: %v", monitor.Name, f.key, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package monitor
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var sessionExpired = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "prognosis",
+	Name:      "session_expired_total",
+	Help:      "Number of times the Prognosis session expired and the bot had to log in again.",
+}, []string{"address"})
+
+func init() {
+	prometheus.MustRegister(sessionExpired)
+}
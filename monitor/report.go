@@ -0,0 +1,252 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/kyokomi/emoji"
+	"github.com/robfig/cron/v3"
+	"golang.org/x/net/context"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Availability is the reliability of a monitor, or of one of its keys, over a period. For SourceSink monitors the keys are
+the nodes. Downtime is the time covered by incidents, MTTR is the average incident length and MTBF the average time up
+between incidents.
+*/
+type Availability struct {
+	Monitor      string         `json:"monitor"`
+	Key          string         `json:"key,omitempty"`
+	Period       string         `json:"period"`
+	From         time.Time      `json:"from"`
+	To           time.Time      `json:"to"`
+	Incidents    int            `json:"incidents"`
+	Downtime     reportDuration `json:"downtime"`
+	MTTR         reportDuration `json:"mttr"`
+	MTBF         reportDuration `json:"mtbf"`
+	Availability float64        `json:"availability"`
+}
+
+// reportDuration is written as 1h2m3s rather than nanoseconds
+type reportDuration time.Duration
+
+func (d reportDuration) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", d.String())), nil
+}
+
+func (d reportDuration) String() string {
+	return time.Duration(d).Truncate(time.Second).String()
+}
+
+var reportPeriods = map[string]func(time.Time) time.Time{
+	"day":   func(t time.Time) time.Time { return t.AddDate(0, 0, -1) },
+	"week":  func(t time.Time) time.Time { return t.AddDate(0, 0, -7) },
+	"month": func(t time.Time) time.Time { return t.AddDate(0, -1, 0) },
+}
+
+/*
+Availability reports on every monitor for the day, week or month up to now, followed by each key that had an incident.
+An empty monitor name reports on all of them.
+*/
+func (s *service) Availability(ctx context.Context, period string, monitorName string) (result []Availability, err error) {
+	if period == "" {
+		period = "week"
+	}
+	start, ok := reportPeriods[period]
+	if !ok {
+		return nil, fmt.Errorf("unknown period %v, expected day, week or month", period)
+	}
+	to := time.Now()
+	from := start(to)
+
+	incidents, err := s.store.GetIncidents(IncidentFilter{From: from, To: to, Monitor: monitorName})
+	if err != nil {
+		return
+	}
+	byMonitor := map[string][]Incident{}
+	for _, i := range s.downtime(incidents) {
+		byMonitor[i.Monitor] = append(byMonitor[i.Monitor], i)
+	}
+
+	for _, m := range s.configuration().Monitors {
+		if (monitorName != "" && m.Name != monitorName) || m.disabled() {
+			continue
+		}
+		list := byMonitor[m.Name]
+		a := availability(list, from, to)
+		a.Monitor, a.Period = m.Name, period
+		result = append(result, a)
+
+		byKey := map[string][]Incident{}
+		var keys []string
+		for _, i := range list {
+			if _, ok := byKey[i.Key]; !ok {
+				keys = append(keys, i.Key)
+			}
+			byKey[i.Key] = append(byKey[i.Key], i)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			a := availability(byKey[key], from, to)
+			a.Monitor, a.Key, a.Period = m.Name, key, period
+			result = append(result, a)
+		}
+	}
+	return
+}
+
+/*
+downtime leaves out the incidents that are not downtime, which are the connection warnings of SourceSink nodes. An open
+incident that is no longer being checked, because its monitor was removed or disabled or its key is no longer failing,
+is ended at its last failure, so it does not keep adding downtime to every report.
+*/
+func (s *service) downtime(incidents []Incident) (result []Incident) {
+	live := map[string]bool{}
+	for _, m := range s.configuration().Monitors {
+		live[m.Name] = !m.disabled()
+	}
+	for _, i := range incidents {
+		if isConnectionKey(i.Key) {
+			continue
+		}
+		if i.Resolved == nil {
+			count, _, err := s.store.GetCount(i.Monitor, i.Key)
+			if !live[i.Monitor] || err != nil || count == 0 {
+				end := i.End
+				if end.IsZero() {
+					end = i.Start
+				}
+				i.Resolved = &end
+			}
+		}
+		result = append(result, i)
+	}
+	return
+}
+
+// isConnectionKey is whether the key is the connection count warning of a SourceSink node, rather than the node itself
+func isConnectionKey(key string) bool {
+	return strings.HasSuffix(key, "-Connections")
+}
+
+// availability works out the stats for the incidents between from and to. Incidents that overlap only count once
+func availability(incidents []Incident, from, to time.Time) (a Availability) {
+	a.From, a.To = from, to
+	a.Incidents = len(incidents)
+
+	type interval struct{ start, end time.Time }
+	var intervals []interval
+	for _, i := range incidents {
+		end := to
+		if i.Resolved != nil && i.Resolved.Before(to) {
+			end = *i.Resolved
+		}
+		start := i.Start
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(start) {
+			intervals = append(intervals, interval{start, end})
+		}
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start.Before(intervals[j].start) })
+
+	var downtime time.Duration
+	var current *interval
+	for i := range intervals {
+		if current != nil && !intervals[i].start.After(current.end) {
+			if intervals[i].end.After(current.end) {
+				current.end = intervals[i].end
+			}
+			continue
+		}
+		if current != nil {
+			downtime += current.end.Sub(current.start)
+		}
+		current = &intervals[i]
+	}
+	if current != nil {
+		downtime += current.end.Sub(current.start)
+	}
+
+	period := to.Sub(from)
+	a.Downtime = reportDuration(downtime)
+	a.Availability = 100 * float64(period-downtime) / float64(period)
+	if a.Incidents > 0 {
+		a.MTTR = reportDuration(downtime / time.Duration(a.Incidents))
+		a.MTBF = reportDuration((period - downtime) / time.Duration(a.Incidents))
+	}
+	return
+}
+
+/*
+sendAvailabilityDigests posts last week's availability to the group of every monitor, on AVAILABILITY_DIGEST_SCHEDULE.
+Monitors that share a group get one message between them.
+*/
+func (s *service) sendAvailabilityDigests(ctx context.Context) {
+	schedule := getAvailabilityDigestSchedule()
+	if schedule == nil {
+		return
+	}
+	for {
+		time.Sleep(time.Until(schedule.Next(time.Now())))
+
+		report, err := s.Availability(ctx, "week", "")
+		if err != nil {
+			log.Printf("Unable to work out the availability digest: %v", err)
+			continue
+		}
+		byName := map[string]*monitors{}
+		for _, m := range s.configuration().Monitors {
+			byName[m.Name] = m
+		}
+
+		var order []string
+		digests := map[string][]string{}
+		first := map[string]*monitors{}
+		for _, a := range report {
+			m, ok := byName[a.Monitor]
+			if !ok {
+				continue
+			}
+			_, target := s.notifierFor(m, 0)
+			target = m.Notifier + target
+			if _, ok := first[target]; !ok {
+				first[target] = m
+				order = append(order, target)
+			}
+			line := fmt.Sprintf("*%v*: %.2f%%", a.Monitor, a.Availability)
+			if a.Key != "" {
+				line = fmt.Sprintf("    %v: %.2f%%", a.Key, a.Availability)
+			}
+			if a.Incidents > 0 {
+				line += fmt.Sprintf(" - %v incidents, %v down, MTTR %v", a.Incidents, a.Downtime, a.MTTR)
+			}
+			digests[target] = append(digests[target], line)
+		}
+
+		for _, target := range order {
+			msg := emoji.Sprintf(":bar_chart: Availability for the last week\n") + strings.Join(digests[target], "\n")
+			s.alert(ctx, first[target], msg)
+		}
+	}
+}
+
+func getAvailabilityDigestSchedule() cron.Schedule {
+	v := os.Getenv("AVAILABILITY_DIGEST_SCHEDULE")
+	if v == "" {
+		v = "0 8 * * 1"
+	}
+	if v == "off" {
+		return nil
+	}
+	schedule, err := cron.ParseStandard(v)
+	if err != nil {
+		log.Printf("Invalid AVAILABILITY_DIGEST_SCHEDULE %v, using 0 8 * * 1. %v", v, err)
+		schedule, _ = cron.ParseStandard("0 8 * * 1")
+	}
+	return schedule
+}
@@ -0,0 +1,115 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAvailability(t *testing.T) {
+	to := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -1)
+	incident := func(start, end time.Duration) Incident {
+		i := Incident{Start: from.Add(start)}
+		if end != 0 {
+			r := from.Add(end)
+			i.Resolved = &r
+		}
+		return i
+	}
+
+	tests := []struct {
+		name         string
+		incidents    []Incident
+		downtime     time.Duration
+		mttr, mtbf   time.Duration
+		availability float64
+	}{
+		{
+			name:         "no incidents",
+			availability: 100,
+		},
+		{
+			name:         "one incident",
+			incidents:    []Incident{incident(time.Hour, 3*time.Hour)},
+			downtime:     2 * time.Hour,
+			mttr:         2 * time.Hour,
+			mtbf:         22 * time.Hour,
+			availability: 100 * 22.0 / 24,
+		},
+		{
+			name:         "overlapping incidents only count once",
+			incidents:    []Incident{incident(time.Hour, 3*time.Hour), incident(2*time.Hour, 4*time.Hour)},
+			downtime:     3 * time.Hour,
+			mttr:         90 * time.Minute,
+			mtbf:         21 * time.Hour / 2,
+			availability: 100 * 21.0 / 24,
+		},
+		{
+			name:         "incident that started before the period",
+			incidents:    []Incident{incident(-time.Hour, time.Hour)},
+			downtime:     time.Hour,
+			mttr:         time.Hour,
+			mtbf:         23 * time.Hour,
+			availability: 100 * 23.0 / 24,
+		},
+		{
+			name:         "open incident runs to the end of the period",
+			incidents:    []Incident{incident(18*time.Hour, 0)},
+			downtime:     6 * time.Hour,
+			mttr:         6 * time.Hour,
+			mtbf:         18 * time.Hour,
+			availability: 75,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := availability(tt.incidents, from, to)
+			if a.Incidents != len(tt.incidents) {
+				t.Errorf("Incidents = %v, want %v", a.Incidents, len(tt.incidents))
+			}
+			if time.Duration(a.Downtime) != tt.downtime || time.Duration(a.MTTR) != tt.mttr || time.Duration(a.MTBF) != tt.mtbf {
+				t.Errorf("Downtime, MTTR, MTBF = %v, %v, %v, want %v, %v, %v", a.Downtime, a.MTTR, a.MTBF, tt.downtime, tt.mttr, tt.mtbf)
+			}
+			if diff := a.Availability - tt.availability; diff > 0.0001 || diff < -0.0001 {
+				t.Errorf("Availability = %v, want %v", a.Availability, tt.availability)
+			}
+		})
+	}
+}
+
+func TestDowntime(t *testing.T) {
+	start := time.Now().Add(-2 * time.Hour)
+	end := time.Now().Add(-time.Hour)
+	incident := func(monitor, key string) Incident {
+		return Incident{Monitor: monitor, Key: key, Start: start, End: end}
+	}
+	s := &service{
+		store: &fakeStore{counts: map[string]int{"SourceSinkATM": 3, "Old": 3, "Quiet": 2}},
+		config: environment{Monitors: []*monitors{
+			{Name: "SourceSink"}, {Name: "Quiet", Mode: modeDisabled},
+		}},
+	}
+	incidents := []Incident{
+		incident("SourceSink", "ATM"),
+		incident("SourceSink", "ATM-Connections"),
+		incident("SourceSink", "POS"),
+		incident("Old", ""),
+		incident("Quiet", ""),
+	}
+
+	got := s.downtime(incidents)
+	var keys []string
+	for _, i := range got {
+		keys = append(keys, i.Monitor+"/"+i.Key)
+		if i.Key == "ATM" && i.Resolved != nil {
+			t.Errorf("%v is still failing, but was ended at %v", i.Key, i.Resolved)
+		}
+		if i.Key != "ATM" && (i.Resolved == nil || !i.Resolved.Equal(end)) {
+			t.Errorf("%v %v is no longer checked, but was not ended at its last failure", i.Monitor, i.Key)
+		}
+	}
+	if !reflect.DeepEqual(keys, []string{"SourceSink/ATM", "SourceSink/POS", "Old/", "Quiet/"}) {
+		t.Errorf("downtime() kept %v", keys)
+	}
+}
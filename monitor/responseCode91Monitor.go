@@ -17,27 +17,90 @@ func NewResponseCode91Monitor() Monitor {
 	return &responseCode91{}
 }
 
-func (s responseCode91) CheckResponse(ctx context.Context, input [][]string) (response []Response, err error) {
-	var codes []string
+/*
+codeThreshold is one entry of the codes param. A code breaches when a row has more than Threshold of it, or when it is
+more than Percent of the responses in the row. Either can be left out.
+*/
+type codeThreshold struct {
+	Code      string  `json:"code"`
+	Threshold *int    `json:"threshold"`
+	Percent   float64 `json:"percent"`
+	Severity  string  `json:"severity"`
+}
+
+func defaultCodes() []codeThreshold {
+	five := 5
+	return []codeThreshold{
+		{Code: "91", Threshold: &five, Severity: SeverityCritical},
+		{Code: "68", Threshold: &five, Severity: SeverityCritical},
+	}
+}
+
+func (s responseCode91) codes(p Params) ([]codeThreshold, error) {
+	if _, ok := p["codes"]; !ok {
+		return defaultCodes(), nil
+	}
+	var codes []codeThreshold
+	err := p.Decode("codes", &codes)
+	return codes, err
+}
+
+func (s responseCode91) ValidateParams(p Params) error {
+	codes, err := s.codes(p)
+	if err != nil {
+		return fmt.Errorf("codes is not a list of codes with a threshold or percent. %v", err)
+	}
+	for _, c := range codes {
+		if c.Code == "" {
+			return fmt.Errorf("every entry in codes needs a code")
+		}
+		if c.Threshold == nil && c.Percent == 0 {
+			return fmt.Errorf("code %v needs a threshold or a percent", c.Code)
+		}
+		if c.Severity != "" && c.Severity != SeverityCritical && c.Severity != SeverityWarning {
+			return fmt.Errorf("unknown severity %q for code %v, expected critical or warning", c.Severity, c.Code)
+		}
+	}
+	return nil
+}
+
+func (s responseCode91) CheckResponse(ctx context.Context, check Check, input [][]string) (response []Response, err error) {
+	codes, err := s.codes(check.Params)
+	if err != nil {
+		return nil, err
+	}
 
+	var records []ResponseCodeRecord
+	totals := map[string]int{}
 	for _, row := range input {
-		codes = append(codes, row[4])
-		switch row[4] {
-		case "91", "68":
-			val, err := strconv.Atoi(row[3])
-			if err != nil {
+		count, err := strconv.Atoi(row[3])
+		if err != nil {
+			continue
+		}
+		records = append(records, ResponseCodeRecord{Id: row[0], Code: row[4], Count: count})
+		totals[row[0]] += count
+	}
+	check.History.SaveResponseCodes(records)
+
+	//Every code gets a response, so the ones that are back under their threshold clear
+	for _, c := range codes {
+		resp := Response{Key: c.Code, Severity: c.Severity}
+		for _, r := range records {
+			if r.Code != c.Code {
 				continue
 			}
-			if val > 5 {
-				response = append(response, Response{
-					Failure:    true,
-					FailureMsg: fmt.Sprintf("%v instances of Code %v found", val, row[4]),
-				})
-				return response, nil
+			percent := 0.0
+			if totals[r.Id] > 0 {
+				percent = 100 * float64(r.Count) / float64(totals[r.Id])
+			}
+			if (c.Threshold != nil && r.Count > *c.Threshold) || (c.Percent > 0 && percent > c.Percent) {
+				resp.Failure = true
+				resp.FailureMsg = fmt.Sprintf("%v instances of Code %v found, %.1f%% of responses", r.Count, c.Code, percent)
+				break
 			}
 		}
+		response = append(response, resp)
 	}
-	response = append(response, Response{})
 	return
 
 }
@@ -0,0 +1,169 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"strings"
+	"time"
+)
+
+/*
+schedule decides when a monitor runs next. A monitor runs every Interval (SLEEP_INTERVAL, or 30s, if it has none).
+Schedule either limits those runs to a time window, for example "Mon-Fri 06:00-22:00", or replaces the interval with a
+cron expression, for example "0 6-22 * * 1-5".
+*/
+type schedule struct {
+	interval time.Duration
+	cron     cron.Schedule
+	window   *window
+}
+
+/*
+newSchedule builds the schedule of the monitor. The interval is never 0, as the monitor would then run on every tick of
+the scheduler, so an Interval that is invalid or not positive leaves the default in place along with the error.
+*/
+func newSchedule(m *monitors) (sc schedule, err error) {
+	sc.interval = envDuration("SLEEP_INTERVAL", defaultInterval)
+	if sc.interval <= 0 {
+		sc.interval = defaultInterval
+	}
+	if m.Interval != "" {
+		d, err := parseInterval(m.Interval)
+		if err != nil {
+			return sc, err
+		}
+		sc.interval = d
+	}
+
+	if m.Schedule == "" {
+		return
+	}
+
+	fields := strings.Fields(m.Schedule)
+	if strings.HasPrefix(m.Schedule, "@") || len(fields) == 5 {
+		sc.cron, err = cron.ParseStandard(m.Schedule)
+		return
+	}
+
+	w, err := parseWindow(fields)
+	sc.window = &w
+	return
+}
+
+const defaultInterval = 30 * time.Second
+
+func parseInterval(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q is not a duration above 0, like 30s or 5m", s)
+	}
+	return d, nil
+}
+
+func (sc schedule) next(last time.Time) time.Time {
+	if sc.cron != nil {
+		return sc.cron.Next(last)
+	}
+	t := last.Add(sc.interval)
+	if sc.window != nil {
+		return sc.window.next(t)
+	}
+	return t
+}
+
+type window struct {
+	days       [7]bool
+	start, end int
+}
+
+// contains checks if t falls in the window. A window where the end is before the start runs past midnight, and belongs
+// to the day it started on.
+func (w window) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if w.start < w.end {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+	return (w.days[day] && minute >= w.start) || (w.days[(day+6)%7] && minute < w.end)
+}
+
+func (w window) next(t time.Time) time.Time {
+	if w.contains(t) {
+		return t
+	}
+	candidate := t.Truncate(time.Minute)
+	for i := 0; i < 7*24*60; i++ {
+		candidate = candidate.Add(time.Minute)
+		if w.contains(candidate) {
+			return candidate
+		}
+	}
+	return t
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseWindow parses "06:00-22:00" or "Mon-Fri 06:00-22:00". Days can be a comma separated list of days and ranges.
+func parseWindow(fields []string) (w window, err error) {
+	times := fields[0]
+	switch len(fields) {
+	case 1:
+		for i := range w.days {
+			w.days[i] = true
+		}
+	case 2:
+		times = fields[1]
+		err = parseDays(fields[0], &w.days)
+		if err != nil {
+			return
+		}
+	default:
+		return w, fmt.Errorf("%v is not a time window or cron expression", strings.Join(fields, " "))
+	}
+
+	parts := strings.Split(times, "-")
+	if len(parts) != 2 {
+		return w, fmt.Errorf("%v is not a time range. Use 06:00-22:00", times)
+	}
+	w.start, err = parseClock(parts[0])
+	if err != nil {
+		return
+	}
+	w.end, err = parseClock(parts[1])
+	return
+}
+
+func parseDays(s string, days *[7]bool) error {
+	for _, item := range strings.Split(strings.ToLower(s), ",") {
+		r := strings.Split(item, "-")
+		from, ok := weekdays[r[0]]
+		if !ok {
+			return fmt.Errorf("%v is not a day of the week", r[0])
+		}
+		to := from
+		if len(r) == 2 {
+			to, ok = weekdays[r[1]]
+			if !ok {
+				return fmt.Errorf("%v is not a day of the week", r[1])
+			}
+		}
+		for d := from; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%v is not a time of day. Use 24 hour time, like 06:00", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		schedule string
+		days     [7]bool
+		start    int
+		end      int
+		err      bool
+	}{
+		{schedule: "06:00-22:00", days: [7]bool{true, true, true, true, true, true, true}, start: 360, end: 1320},
+		{schedule: "Mon-Fri 06:00-22:00", days: [7]bool{false, true, true, true, true, true, false}, start: 360, end: 1320},
+		{schedule: "sat,sun 22:00-02:00", days: [7]bool{true, false, false, false, false, false, true}, start: 1320, end: 120},
+		{schedule: "Fri-Mon 00:00-01:30", days: [7]bool{true, true, false, false, false, true, true}, end: 90},
+		{schedule: "Mon,Wed-Thu 08:00-09:00", days: [7]bool{false, true, false, true, true, false, false}, start: 480, end: 540},
+		{schedule: "Someday 06:00-22:00", err: true},
+		{schedule: "Mon-Fri 6am-10pm", err: true},
+		{schedule: "06:00", err: true},
+		{schedule: "Mon Tue 06:00-22:00", err: true},
+	}
+	for _, tt := range tests {
+		w, err := parseWindow(strings.Fields(tt.schedule))
+		if (err != nil) != tt.err {
+			t.Errorf("parseWindow(%q) error = %v", tt.schedule, err)
+			continue
+		}
+		if err == nil && (w.days != tt.days || w.start != tt.start || w.end != tt.end) {
+			t.Errorf("parseWindow(%q) = %+v", tt.schedule, w)
+		}
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	//2024-01-05 is a Friday
+	at := func(s string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		schedule string
+		t        string
+		want     bool
+	}{
+		{"Mon-Fri 06:00-22:00", "2024-01-05 06:00", true},
+		{"Mon-Fri 06:00-22:00", "2024-01-05 22:00", false},
+		{"Mon-Fri 06:00-22:00", "2024-01-06 12:00", false},
+		{"Fri 22:00-02:00", "2024-01-05 23:00", true},
+		{"Fri 22:00-02:00", "2024-01-06 01:59", true},
+		{"Fri 22:00-02:00", "2024-01-06 02:00", false},
+		{"Fri 22:00-02:00", "2024-01-05 01:00", false},
+	}
+	for _, tt := range tests {
+		w, err := parseWindow(strings.Fields(tt.schedule))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := w.contains(at(tt.t)); got != tt.want {
+			t.Errorf("%q contains(%v) = %v, want %v", tt.schedule, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	at := func(s string) time.Time {
+		layout := "2006-01-02 15:04"
+		if len(s) > len(layout) {
+			layout += ":05"
+		}
+		d, err := time.ParseInLocation(layout, s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		name     string
+		monitor  monitors
+		last     string
+		want     string
+		schedErr bool
+	}{
+		{
+			name:    "interval",
+			monitor: monitors{Interval: "5m"},
+			last:    "2024-01-05 10:00",
+			want:    "2024-01-05 10:05",
+		},
+		{
+			name:    "interval in the window",
+			monitor: monitors{Interval: "5m", Schedule: "Mon-Fri 06:00-22:00"},
+			last:    "2024-01-05 10:00",
+			want:    "2024-01-05 10:05",
+		},
+		{
+			name:    "waits for the window to open",
+			monitor: monitors{Interval: "5m", Schedule: "Mon-Fri 06:00-22:00"},
+			last:    "2024-01-05 21:58",
+			want:    "2024-01-08 06:00",
+		},
+		{
+			name:    "cron",
+			monitor: monitors{Schedule: "0 6-22 * * 1-5"},
+			last:    "2024-01-05 10:15",
+			want:    "2024-01-05 11:00",
+		},
+		{
+			name:    "default interval",
+			monitor: monitors{},
+			last:    "2024-01-05 10:00",
+			want:    "2024-01-05 10:00:30",
+		},
+		{
+			name:     "invalid interval",
+			monitor:  monitors{Interval: "often"},
+			schedErr: true,
+		},
+		{
+			name:     "interval of 0",
+			monitor:  monitors{Interval: "0s"},
+			schedErr: true,
+		},
+		{
+			name:     "invalid cron",
+			monitor:  monitors{Schedule: "0 6-22 * * 1-9"},
+			schedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := newSchedule(&tt.monitor)
+			if (err != nil) != tt.schedErr {
+				t.Fatalf("newSchedule() error = %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if got := sc.next(at(tt.last)); !got.Equal(at(tt.want)) {
+				t.Errorf("next(%v) = %v, want %v", tt.last, got, tt.want)
+			}
+		})
+	}
+}
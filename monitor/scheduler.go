@@ -0,0 +1,198 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/net/context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+type checkResult struct {
+	monitor  *monitors
+	start    time.Time
+	duration time.Duration
+	err      error
+}
+
+type scheduled struct {
+	monitor  *monitors
+	schedule schedule
+	next     time.Time
+	running  bool
+}
+
+/*
+runScheduler starts MONITOR_WORKERS workers and hands every monitor to them when its schedule says it is due. A
+monitor is never queued again while it is still running, so a dashboard that is slow to respond only delays itself.
+*/
+func (s *service) runScheduler(ctx context.Context) {
+	jobs := make(chan *monitors)
+	results := make(chan checkResult)
+	for i := 0; i < getWorkerCount(); i++ {
+		go func() {
+			for m := range jobs {
+				results <- s.runMonitor(ctx, m)
+			}
+		}()
+	}
+
+	configs := s.configuration().Monitors
+	entries := scheduleMonitors(configs, map[string]*scheduled{})
+
+	var queue []*monitors
+	var summary []checkResult
+	lastSummary := time.Now()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		var send chan *monitors
+		var head *monitors
+		if len(queue) > 0 {
+			send = jobs
+			head = queue[0]
+		}
+
+		select {
+		case send <- head:
+			queue = queue[1:]
+
+		case configs = <-s.reload:
+			entries = scheduleMonitors(configs, entries)
+
+		case r := <-results:
+			summary = append(summary, r)
+			if e, ok := entries[r.monitor.Name]; ok {
+				e.running = false
+				e.next = e.schedule.next(r.start)
+			}
+
+		case now := <-ticker.C:
+			for _, m := range configs {
+				e, ok := entries[m.Name]
+				if ok && !e.running && !now.Before(e.next) {
+					e.running = true
+					queue = append(queue, m)
+				}
+			}
+			if now.Sub(lastSummary) >= time.Minute {
+				logSummary(lastSummary, summary)
+				summary = nil
+				lastSummary = now
+			}
+		}
+	}
+}
+
+/*
+scheduleMonitors builds the schedule for the monitors. Monitors that are already scheduled keep their entry, so a config
+reload does not make the unchanged monitors run early or twice.
+*/
+func scheduleMonitors(configs []*monitors, current map[string]*scheduled) map[string]*scheduled {
+	entries := map[string]*scheduled{}
+	for _, m := range configs {
+		if m.disabled() {
+			continue
+		}
+		e, ok := current[m.Name]
+		if ok && e.monitor == m {
+			entries[m.Name] = e
+			continue
+		}
+
+		sc, err := newSchedule(m)
+		if err != nil {
+			log.Printf("Invalid schedule for %v, running it every %v. %v", m.Name, sc.interval, err)
+		}
+		entries[m.Name] = &scheduled{monitor: m, schedule: sc, next: sc.next(time.Now().Add(-sc.interval))}
+		if ok {
+			//The previous version of the monitor is still running, wait for it before starting the new one
+			entries[m.Name].running = e.running
+		}
+	}
+	return entries
+}
+
+func (s *service) runMonitor(ctx context.Context, monitor *monitors) checkResult {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, envDuration("MONITOR_TIMEOUT", 2*time.Minute))
+	defer cancel()
+
+	response, err := s.checkMonitor(ctx, monitor)
+
+	//If there is an error fetching data, lets handle it, but not use the results to determine the system health
+	if err != nil {
+		s.techError(ctx)
+		return checkResult{monitor: monitor, start: start, duration: time.Since(start), err: err}
+	}
+	s.resetTechErrors()
+
+	//A monitor that a reload removed while it was running would open incidents that nothing resolves
+	if !s.configured(monitor.Name) {
+		return checkResult{monitor: monitor, start: start, duration: time.Since(start)}
+	}
+	s.handleResponses(ctx, monitor, response)
+	s.resolveUnreported(ctx, monitor, response)
+	return checkResult{monitor: monitor, start: start, duration: time.Since(start)}
+}
+
+func (s *service) configured(name string) bool {
+	for _, m := range s.configuration().Monitors {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *service) techError(ctx context.Context) {
+	s.mu.Lock()
+	s.techErrCount++
+	count := s.techErrCount
+	s.mu.Unlock()
+
+	log.Printf("Tech Error count is %v", count)
+	if count == 10 {
+		s.failover(ctx, s.client(), "10 consecutive failures detected")
+	}
+}
+
+func (s *service) resetTechErrors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.techErrCount != 0 {
+		log.Println("setting tech error count to 0")
+		s.techErrCount = 0
+	}
+}
+
+func logSummary(since time.Time, results []checkResult) {
+	var b bytes.Buffer
+	failed := 0
+	for _, r := range results {
+		status := "ok"
+		if r.err != nil {
+			status = r.err.Error()
+			failed++
+		}
+		b.WriteString(fmt.Sprintf("\n  %v: %v (%v)", r.monitor.Name, r.duration.Truncate(time.Millisecond), status))
+	}
+	log.Printf("%v monitor checks in the last %v, %v failed.%v",
+		len(results), time.Since(since).Truncate(time.Second), failed, b.String())
+}
+
+func getWorkerCount() int {
+	v := os.Getenv("MONITOR_WORKERS")
+	if v == "" {
+		return 4
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 1 {
+		log.Printf("Invalid MONITOR_WORKERS %v, using 4", v)
+		return 4
+	}
+	return i
+}
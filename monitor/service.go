@@ -1,427 +1,429 @@
 package monitor
 
 import (
-	"bytes"
-	"crypto/tls"
-	"encoding/json"
 	"fmt"
-	"github.com/antchfx/htmlquery"
 	"github.com/kyokomi/emoji"
-	"github.com/weAutomateEverything/go2hal/callout"
+	"github.com/weAutomateEverything/prognosisHalBot/notifier"
+	"github.com/weAutomateEverything/prognosisHalBot/prognosis"
+	"github.com/weAutomateEverything/prognosisHalBot/silence"
 	"golang.org/x/net/context"
-	"io/ioutil"
+	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
+	"path"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 )
 
+/*
+SiteMonitor is implemented by Monitor types that compare the same widget on the dashboards of more than one site. They
+are given the rows of every one of the monitor's Sites at once.
+*/
+type SiteMonitor interface {
+	Monitor
+	CheckSites(ctx context.Context, check Check, sites []Site) (response []Response, err error)
+}
+
+type Site struct {
+	Name string
+	Rows [][]string
+}
+
+// Monitor checks the rows of a Prognosis widget. The rows are empty when the widget only has its header rows, which means no traffic
 type Monitor interface {
-	CheckResponse(ctx context.Context, s [][]string) (response []Response, err error)
+	CheckResponse(ctx context.Context, check Check, s [][]string) (response []Response, err error)
 	GetName() string
 }
 
 type Service interface {
+	ReloadConfig(ctx context.Context) (ConfigChanges, error)
+	Announce(ctx context.Context, monitor string, msg string)
+	AddMaintenanceWindow(ctx context.Context, w MaintenanceWindow) (MaintenanceWindow, error)
+	MaintenanceWindows(ctx context.Context) ([]MaintenanceWindow, error)
+	DeleteMaintenanceWindow(ctx context.Context, id string) error
+	ImportCalendar(ctx context.Context, calendar string, ics io.Reader, monitors, nodes []string) ([]MaintenanceWindow, error)
+	Incidents(ctx context.Context, filter IncidentFilter) ([]Incident, error)
+	Availability(ctx context.Context, period string, monitor string) ([]Availability, error)
+	History(ctx context.Context, monitor string, from, to time.Time) (interface{}, error)
 }
 
 type Response struct {
 	Key        string
 	Failure    bool
 	FailureMsg string
+	//Severity is critical (the default) or warning. Warnings are alerted on, but never invoke a callout
+	Severity string
+}
+
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+)
+
+// severityRank orders the severities, so the worst one seen can be kept
+func severityRank(severity string) int {
+	if severity == SeverityWarning {
+		return 1
+	}
+	return 2
 }
 
 type service struct {
-	store Store
+	store    Store
+	silences silence.Store
+
+	mu sync.Mutex
+	//failoverMu makes sure only one goroutine moves us between Prognosis hosts at a time
+	failoverMu sync.Mutex
+	reloadMu   sync.Mutex
+	reload     chan []*monitors
 
-	monitors map[string]Monitor
+	monitors  map[string]Monitor
+	notifiers map[string]notifier.Notifier
 
 	failing    bool
-	cookie     []*http.Cookie
+	clients    []prognosis.Client
+	hosts      []hostHealth
 	config     environment
 	currentEnv int
 
 	techErrCount int
+	//missing holds the monitors whose widget was not found on the current host, by the monitor name
+	missing map[string]bool
+	//fetched holds the widget each monitor last read successfully, by the monitor name
+	fetched map[string]site
+
+	maintenance []MaintenanceWindow
+	//suppressed holds the failures seen during each active maintenance window, by the window id
+	suppressed map[string]map[string]suppressedFailure
+
+	//parents holds the parent incidents of the correlations that have failures, by the correlation name
+	parents       map[string]*parentIncident
+	correlationMu sync.Mutex
 }
 
-func NewService(store Store, monitors ...Monitor) Service {
-	s := service{
-		store: store,
+func NewService(store Store, silences silence.Store, notifiers map[string]notifier.Notifier, checks ...Monitor) Service {
+	s := &service{
+		store:     store,
+		silences:  silences,
+		notifiers: notifiers,
+		reload:    make(chan []*monitors, 1),
+
+		missing:    map[string]bool{},
+		fetched:    map[string]site{},
+		suppressed: map[string]map[string]suppressedFailure{},
+		parents:    map[string]*parentIncident{},
 	}
 
 	s.monitors = map[string]Monitor{}
 
-	for _, m := range monitors {
+	for _, m := range checks {
 		s.monitors[m.GetName()] = m
 	}
 
-	cfg := os.Getenv("CONFIG_URL")
-	if cfg == "" {
-		panic("CONFIG_URL environment variable is not set.")
-	}
-	var configs environment
-
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	http.DefaultClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		return http.ErrUseLastResponse
-	}
 	http.DefaultClient.Timeout = 30 * time.Second
 
-	resp, err := http.Get(cfg)
+	configs, err := downloadConfig()
 	if err != nil {
 		panic(err)
 	}
-
-	defer resp.Body.Close()
-
-	err = json.NewDecoder(resp.Body).Decode(&configs)
+	err = s.validateConfig(configs)
 	if err != nil {
 		panic(err)
 	}
 
 	s.config = configs
 
+	for _, address := range configs.Address {
+		s.clients = append(s.clients, prognosis.NewClient(address, getUsername(), getPassword()))
+		s.hosts = append(s.hosts, hostHealth{healthy: true})
+	}
+
 	go func() { s.runChecks() }()
 
 	return s
 }
 
 func (s *service) runChecks() {
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	http.DefaultClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		return http.ErrUseLastResponse
-	}
-
 	ctx := context.Background()
 	//Login - get the cookie for auth
 	s.getLoginCookie(ctx)
-
-	for true {
-		s.checkPrognosis()
-		sleep()
-	}
+	s.restoreParents(ctx)
+	s.migrateCode91Keys(ctx)
+
+	go s.failback(ctx)
+	go s.escalateParents(ctx)
+	go s.reloadConfigPeriodically(ctx)
+	go s.watchMaintenanceWindows(ctx)
+	go s.sendAvailabilityDigests(ctx)
+	go s.sendDailySummaries(ctx)
+	s.runScheduler(ctx)
 }
 
-func (s *service) checkPrognosis() {
-	log.Println("Starting Prognosis")
-
-	ctx := context.Background()
-
-	for _, monitor := range s.config.Monitors {
-		response, err := s.checkMonitor(ctx, monitor)
-
-		//If there is an error fetching data, lets handle it, but not use the results to determine the system health
-		if err != nil {
-			s.techErrCount++
-			log.Printf("Tech Error count is %v", s.techErrCount)
-			if s.techErrCount == 10 {
-				s.sendMessage(ctx, "10 failures detected. Attempting login to find a new host", getErrorGroup())
-				panic("10 consecutive failures")
+func (s *service) handleResponses(ctx context.Context, monitor *monitors, response []Response) {
+	for _, resp := range response {
+		if resp.Failure {
+			s.handleFailed(ctx, monitor, resp)
+		} else {
+			count, t, err := s.store.GetCount(monitor.Name, resp.Key)
+			if err != nil {
+				continue
 			}
-			continue
-		}
-		log.Println("setting tech error count to 0")
-		s.techErrCount = 0
-
-		for _, resp := range response {
-			if resp.Failure {
-				s.handleFailed(ctx, monitor, resp)
-			} else {
-				_, t, err := s.store.GetCount(monitor.Name, resp.Key)
+			if count > 0 {
+				err = s.store.ResolveIncident(monitor.Name, resp.Key)
 				if err != nil {
-					continue
+					log.Printf("Unable to resolve the incident for %v %v: %v", monitor.Name, resp.Key, err)
 				}
-				d := time.Since(t).Truncate(time.Second)
-				sent, err := s.store.IsMessageSent(monitor.Name, resp.Key)
-				if err != nil {
-					s.sendMessage(ctx, fmt.Sprintf("Error checking if a message has been sent. %v", err.Error()), getErrorGroup())
-					continue
-				}
-				if sent {
-					s.sendMessage(ctx, emoji.Sprintf(":white_check_mark: No issues detected for %v %v. Errors occurred for %v", monitor.Name, resp.Key, d.String()), monitor.Group)
-				}
-				s.store.ZeroCount(monitor.Name, resp.Key)
-			}
-		}
-	}
-}
-
-func (s *service) handleFailed(ctx context.Context, monitor *monitors, response Response) {
-	err := s.store.IncreaseCount(monitor.Name, response.Key)
-	if err != nil {
-		log.Println(err)
-		s.sendMessage(ctx, err.Error(), getErrorGroup())
-		return
-	}
-	_, t, err := s.store.GetCount(monitor.Name, response.Key)
-	d := time.Since(t).Truncate(time.Second)
-
-	if err != nil {
-		log.Println(err)
-		s.sendMessage(ctx, err.Error(), getErrorGroup())
-		return
-	}
-	//Ignore the first 2 errors - this should make the alerts less noisy
-	if d > 30*time.Second {
-		log.Printf("Sendign warning for %v", monitor.Name)
-		s.sendMessage(ctx, emoji.Sprintf(":x: %v. Error has been occurring for %v.", response.FailureMsg, d.String()), monitor.Group)
-		s.store.SetMessageSent(monitor.Name, response.Key)
-	}
-
-	//After 15 alerts, lets invoke callout
-	if d > 3*time.Minute {
-		calloutInvoked, err := s.store.IsCalloutInvoked(monitor.Name, response.Key)
-		if err != nil {
-			s.sendMessage(ctx, fmt.Sprintf("Error checking if callout has been invoked: %v", err.Error()), getErrorGroup())
-			return
-
-		}
-		if !calloutInvoked {
-			log.Printf("Invoking callout for %v %v\n", monitor.Name, response.Key)
-
-			c := callout.SendCalloutRequest{
-				Message: fmt.Sprintf("Prognosis Issue Detected. %v", response.FailureMsg),
-				Title:   response.FailureMsg,
 			}
-
-			b, err := json.Marshal(c)
-			if err != nil {
-				return
-			}
-			resp, err := http.Post(fmt.Sprintf("%v/api/callout/%v", os.Getenv("HAL_ENDPOINT"), monitor.Group),
-				"application/json", bytes.NewReader(b))
+			s.clearSymptom(ctx, monitor, resp.Key)
+			d := time.Since(t).Truncate(time.Second)
+			sent, err := s.store.IsMessageSent(monitor.Name, resp.Key)
 			if err != nil {
-				return
+				s.sendMessage(ctx, fmt.Sprintf("Error checking if a message has been sent. %v", err.Error()), getErrorGroup())
+				continue
 			}
-			resp.Body.Close()
-			err = s.store.SetCalloutInvoked(monitor.Name, response.Key)
-			if err != nil {
-				s.sendMessage(ctx, fmt.Sprintf("Error setting callout invoked: %v", err.Error()), getErrorGroup())
+			if sent {
+				s.alert(ctx, monitor, emoji.Sprintf(":white_check_mark: No issues detected for %v %v. Errors occurred for %v", monitor.Name, resp.Key, d.String()))
 			}
+			s.store.ZeroCount(monitor.Name, resp.Key)
 		}
 	}
-	return
 }
 
-func (s *service) getLoginCookie(ctx context.Context) (err error) {
-	for true {
-		for i, x := range s.config.Address {
-			log.Println("Logging in")
-			v := url.Values{}
-			v.Add("UserName", getUsername())
-			v.Add("Password", getPassword())
-			v.Add("Destination", "View Systems")
-			req, err := http.NewRequest("POST", fmt.Sprintf("%v/Prognosis/Login?returnUrl=/Prognosis/", x), strings.NewReader(v.Encode()))
-			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+func (s *service) getLoginCookie(ctx context.Context) {
+	for !s.loginNext(ctx, len(s.clientList())-1, false) {
+		s.sendMessage(ctx, "Unable to successfully log into prognosis... will try again in 60 seconds", getErrorGroup())
+		time.Sleep(60 * time.Second)
+	}
+}
 
-			resp, err := http.DefaultClient.Do(req)
+func (s *service) checkMonitor(ctx context.Context, monitor *monitors) (response []Response, err error) {
+	check, ok := s.monitors[monitor.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown monitor type %v", monitor.Type)
+	}
+	log.Printf(check.GetName())
 
+	if sm, ok := check.(SiteMonitor); ok {
+		var sites []Site
+		for _, w := range monitor.widgets() {
+			rows, err := s.fetchRows(ctx, monitor, w)
 			if err != nil {
-				s.sendMessage(ctx, "prognosis error - "+err.Error(), getErrorGroup())
-				continue
-			}
-			if len(resp.Cookies()) == 0 {
-				s.sendMessage(ctx, "Prognosis error - No cookie found on response", getErrorGroup())
-				continue
+				return nil, err
 			}
-			s.currentEnv = i
-			defer resp.Body.Close()
-			s.cookie = resp.Cookies()
-			return nil
+			sites = append(sites, Site{Name: w.Name, Rows: rows})
 		}
-		s.sendMessage(ctx, "Unable to successfully log into prognosis... will try again in 60 seconds", getErrorGroup())
-		time.Sleep(60 * time.Second)
+		return sm.CheckSites(ctx, s.newCheck(monitor), sites)
 	}
-	return nil
 
+	input, err := s.fetchRows(ctx, monitor, monitor.widgets()[0])
+	if err != nil {
+		return nil, err
+	}
+	return check.CheckResponse(ctx, s.newCheck(monitor), input)
 }
 
-func (s *service) checkMonitor(ctx context.Context, monitor *monitors) (response []Response, err error) {
+// fetchRows reads the rows of a widget, trying up to 10 times
+func (s *service) fetchRows(ctx context.Context, monitor *monitors, w site) (input [][]string, err error) {
 	count := 0
 	for count < 10 {
 		if count > 0 {
-			time.Sleep(1 * time.Second)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(1 * time.Second):
+			}
 		}
 		count++
-		guid, err := s.getGuidForMonitor(ctx, monitor)
-		if err != nil {
+		current := s.client()
+		guid, err := s.getGuidForMonitor(ctx, w)
+		if _, ok := err.(prognosis.GUIDNotFoundError); ok {
+			//The widget is not on the dashboard, trying again will not find it
 			log.Println(err)
-			continue
-		}
-		if monitor.ObjectType == "" {
-			monitor.ObjectType = "#"
+			s.widgetMissing(ctx, current, monitor.Name, w)
+			return nil, err
 		}
-		url := fmt.Sprintf("%v/Prognosis/DashboardView/%v",
-			s.getEndpoint(),
-			guid,
-		)
-		req, err := http.NewRequest("GET", url, strings.NewReader(""))
-		if err != nil {
-			continue
-		}
-		for _, c := range s.cookie {
-			req.AddCookie(c)
-		}
-
-		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
 			log.Println(err)
 			continue
 		}
-		var data map[string]interface{}
-
-		body, err := ioutil.ReadAll(resp.Body)
+		var view prognosis.DashboardView
+		err = s.withSession(ctx, func(c prognosis.Client) (err error) {
+			view, err = c.FetchDashboardView(ctx, guid)
+			return
+		})
 		if err != nil {
 			log.Println(err)
 			continue
 		}
 
-		log.Println(string(body))
-
-		err = json.Unmarshal(body, &data)
-
-		resp.Body.Close()
+		input, err = view.Rows(w.Id)
 		if err != nil {
-			log.Println(err)
-			continue
-		}
-
-		if data == nil {
-			err = fmt.Errorf("data nil for dashboard %v, id %v", monitor.Dashboard, guid)
-			log.Println(err)
-			continue
-		}
-
-		//First the root element - this should include a item called Data
-		key := monitor.Id
-		if strings.HasPrefix(key, "id_") {
-			key = strings.Replace(key, "id_", "", 1)
-		}
-		root, ok := data[key]
-		if !ok {
-			log.Println("No valid root element found")
-			continue
-		}
-		rootMap := root.(map[string]interface{})
-
-		dataObject, ok := rootMap["Data"]
-		if !ok {
-			log.Println("No Data object found")
-			continue
-		}
-
-		dataArray := (dataObject).([]interface{})
-
-		if len(dataArray) == 0 {
 			//Sometimes, it takes prognosis a while to wake up... so the first 10 no data we can ignore
-			log.Printf("Length of data is 0 for dashboard %v, graph %v", monitor.Dashboard, monitor.Name)
+			log.Printf("%v for dashboard %v, graph %v", err, w.Dashboard, monitor.Name)
 			continue
 		}
-		dataElements := dataArray[0].([]interface{})
-		if len(dataElements) == 2 {
-			continue
-		}
-		var input [][]string
-		for _, row := range dataElements[2:] {
-			var val []string
-			for _, m := range row.([]interface{}) {
-				val = append(val, m.(string))
-			}
-			input = append(input, val)
-		}
-		monitor.lastSuccess = time.Now().UnixNano()
-		monitor := s.monitors[monitor.Type]
-		log.Printf(monitor.GetName())
-		return monitor.CheckResponse(ctx, input)
+		s.widgetFound(monitor.Name, w)
+		return input, nil
 
 	}
 	s.sendMessage(ctx, fmt.Sprintf("No data found after 10 attempts for dashboard %v", monitor.Name), getErrorGroup())
-	err = NoResultsError{Messsage: fmt.Sprintf("no data found for %v, graph %v", monitor.Dashboard, getErrorGroup())}
+	err = NoResultsError{Messsage: fmt.Sprintf("no data found for %v, graph %v", w.Dashboard, getErrorGroup())}
 	return nil, err
 
 }
 
-func (s *service) getGuidForMonitor(ctx context.Context, monitor *monitors) (guid string, err error) {
-	url := fmt.Sprintf("%v/Prognosis/Dashboard/Content/%v", s.getEndpoint(), monitor.Dashboard)
-	req, err := http.NewRequest("GET", url, strings.NewReader(""))
-	for _, c := range s.cookie {
-		req.AddCookie(c)
-	}
-	resp, err := http.DefaultClient.Do(req)
-	defer resp.Body.Close()
+func (s *service) getGuidForMonitor(ctx context.Context, w site) (guid string, err error) {
+	err = s.withSession(ctx, func(c prognosis.Client) (err error) {
+		guid, err = c.ResolveWidgetGUID(ctx, w.Dashboard, w.Id)
+		return
+	})
+	return
 
-	doc, err := htmlquery.Parse(resp.Body)
+}
 
-	if err != nil {
-		return "", nil
+/*
+withSession runs the request against the current Prognosis host. If the session has expired, we log in again on the same
+host and retry the request once, rather than letting it count as a technical error.
+*/
+func (s *service) withSession(ctx context.Context, request func(c prognosis.Client) error) error {
+	c := s.client()
+	err := request(c)
+	if err != prognosis.ErrSessionExpired {
+		return err
 	}
 
-	nodes := htmlquery.Find(doc, fmt.Sprintf("//div[@id='%v']", monitor.Id))
-	for _, node := range nodes {
-		child := node.FirstChild
-		for child.Data != "script" {
-			child = child.NextSibling
-			if child == nil {
-				break
-			}
-		}
-		if child != nil {
-			s := child.FirstChild.Data
-			lines := strings.Split(s, "\n")
-			for _, line := range lines {
-				if strings.Index(line, "guid") != -1 {
-					guid = line[strings.Index(line, "guid")+5:]
-					guid = strings.Replace(guid, "\"", "", -1)
-					guid = strings.Replace(guid, ",", "", -1)
-					guid = strings.TrimSpace(guid)
-					return
-				}
-			}
-		}
-	}
-	msg := fmt.Sprintf("no guid found for %v on dashboard %v. Restarting Bot", monitor.Name, getErrorGroup())
-	s.sendMessage(ctx, msg, getErrorGroup())
-	panic(msg)
-	return
+	sessionExpired.WithLabelValues(c.Address()).Inc()
+	log.Printf("Prognosis session expired on %v, logging in again", c.Address())
 
+	err = c.Login(ctx)
+	if err != nil {
+		log.Printf("Unable to log in to %v after the session expired: %v", c.Address(), err)
+		return err
+	}
+	return request(c)
 }
 
+// sendMessage posts a message to a HAL group. It is used for the technical errors that go to the ERROR_GROUP
 func (s *service) sendMessage(ctx context.Context, message string, group int64) {
-	message = strings.Replace(message, "_", " ", -1)
-
-	resp, err := http.Post(fmt.Sprintf("%v/api/alert/%v", os.Getenv("HAL_ENDPOINT"), group),
-		"application/text", strings.NewReader(message))
+	err := s.notifiers[notifier.HAL].Alert(ctx, strconv.FormatInt(group, 10), message)
 	if err != nil {
 		log.Printf("error sending message %v to group %v - error %v", message, group, err)
-		return
 	}
+}
 
-	resp.Body.Close()
-
+// alert sends the message through the notifier the monitor is configured to use
+func (s *service) alert(ctx context.Context, monitor *monitors, message string) {
+	s.alertGroup(ctx, monitor, 0, message)
 }
 
-func sleep() {
-	sleep := os.Getenv("SLEEP_INTERVAL")
-	if sleep == "" {
+// alertGroup sends the message to a group other than the monitors own, unless the group is 0
+func (s *service) alertGroup(ctx context.Context, monitor *monitors, group int64, message string) {
+	if monitor.shadow() {
+		s.shadowAlert(ctx, monitor, message)
 		return
 	}
+	n, target := s.notifierFor(monitor, group)
+	err := n.Alert(ctx, target, message)
+	if err != nil {
+		log.Printf("error sending alert %v for %v to %v - error %v", message, monitor.Name, target, err)
+	}
+}
 
-	i, err := strconv.Atoi(sleep)
+func (s *service) calloutGroup(ctx context.Context, monitor *monitors, group int64, title, message string) error {
+	if monitor.shadow() {
+		s.shadowAlert(ctx, monitor, fmt.Sprintf("A callout would have been invoked. %v", title))
+		return nil
+	}
+	n, target := s.notifierFor(monitor, group)
+	err := n.Callout(ctx, target, title, message)
 	if err != nil {
-		log.Printf("Error formatting sleep value - not sleeping. %v", err.Error())
+		log.Printf("error invoking callout %v for %v on %v - error %v", title, monitor.Name, target, err)
+	}
+	return err
+}
+
+/*
+shadowAlert is used for monitors in shadow mode, which are being tried out before they are allowed to page anyone. The
+alerts go to the ERROR_GROUP, or only to the logs if SHADOW_NOTIFIER is log.
+*/
+func (s *service) shadowAlert(ctx context.Context, monitor *monitors, message string) {
+	message = fmt.Sprintf("[SHADOW] %v: %v", monitor.Name, message)
+	if os.Getenv("SHADOW_NOTIFIER") == notifier.Log {
+		log.Println(message)
 		return
 	}
-	time.Sleep(time.Duration(i) * time.Second)
+	s.sendMessage(ctx, message, getErrorGroup())
+}
+
+/*
+Announce posts the message once to the group of every monitor the pattern matches. If it matches no monitors, the message
+goes to the ERROR_GROUP so it is not lost.
+*/
+func (s *service) Announce(ctx context.Context, pattern string, msg string) {
+	sent := map[string]bool{}
+	for _, m := range s.configuration().Monitors {
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, m.Name); !ok {
+				continue
+			}
+		}
+		_, target := s.notifierFor(m, 0)
+		if sent[m.Notifier+target] {
+			continue
+		}
+		sent[m.Notifier+target] = true
+		s.alert(ctx, m, msg)
+	}
+	if len(sent) == 0 {
+		s.sendMessage(ctx, msg, getErrorGroup())
+	}
+}
+
+func (s *service) notifierFor(monitor *monitors, group int64) (notifier.Notifier, string) {
+	name := monitor.Notifier
+	if name == "" {
+		name = notifier.HAL
+	}
+	target := monitor.Target
+	if group != 0 {
+		target = strconv.FormatInt(group, 10)
+	} else if target == "" {
+		target = strconv.FormatInt(monitor.Group, 10)
+	}
+	return s.notifiers[name], target
 }
 
 type httpLogger struct {
 	log *log.Logger
 }
 
-func (s service) getEndpoint() string {
-	return s.config.Address[s.currentEnv]
+func (s *service) client() prognosis.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clients[s.currentEnv]
+}
+
+/*
+envDuration reads a duration, like 15m, from the environment. A plain number is taken as seconds, which is how
+SLEEP_INTERVAL has always been set. An unset or invalid value gives def.
+*/
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	if i, err := strconv.Atoi(v); err == nil {
+		return time.Duration(i) * time.Second
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Invalid %v %v, using %v. %v", name, v, def, err)
+		return def
+	}
+	return d
 }
 
 func getUsername() string {
@@ -454,17 +456,6 @@ func (NoResultsError) RuntimeError() {
 
 }
 
-type environment struct {
-	Address  []string
-	Monitors []*monitors
-}
-
-type monitors struct {
-	Type, Dashboard, Id, Name, ObjectType string
-	Group                                 int64
-	lastSuccess                           int64
-}
-
 func getTimeout() context.Context {
 	c, _ := context.WithTimeout(context.TODO(), 30*time.Second)
 	return c
@@ -0,0 +1,27 @@
+package monitor
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEnvDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", time.Minute},
+		{"15m", 15 * time.Minute},
+		{"90", 90 * time.Second},
+		{"0", 0},
+		{"soon", time.Minute},
+	}
+	for _, tt := range tests {
+		os.Setenv("TEST_DURATION", tt.value)
+		if got := envDuration("TEST_DURATION", time.Minute); got != tt.want {
+			t.Errorf("envDuration(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+	os.Unsetenv("TEST_DURATION")
+}
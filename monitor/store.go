@@ -2,12 +2,17 @@ package monitor
 
 import (
 	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
 	"time"
 )
 
 type Store interface {
-	SaveRateData(d data)
-	SaveResponceCodeData([]string)
+	SaveRateData(r RateRecord)
+	SaveResponceCodeData(r []ResponseCodeRecord)
+	GetRateData(monitor string, from, to time.Time) ([]RateRecord, error)
+	GetResponseCodeData(monitor string, from, to time.Time) ([]ResponseCodeRecord, error)
+	SaveConnectionData(r []ConnectionRecord)
+	GetConnectionData(monitor string, from, to time.Time) ([]ConnectionRecord, error)
 	GetCount(id string, key string) (int, time.Time, error)
 	IncreaseCount(id string, key string) error
 	ZeroCount(id string, key string) error
@@ -15,9 +20,24 @@ type Store interface {
 	SetCalloutInvoked(is string, key string) error
 	IsMessageSent(id string, key string) (bool, error)
 	IsCalloutInvoked(id string, key string) (bool, error)
+	GetEscalationState(id string, key string) ([]escalationState, error)
+	SetEscalationStepFired(id string, key string, step int) error
+	AddMaintenanceWindows(windows []MaintenanceWindow) error
+	GetMaintenanceWindows() ([]MaintenanceWindow, error)
+	DeleteMaintenanceWindow(id string) error
+	ReplaceCalendar(calendar string, windows []MaintenanceWindow) error
+	RecordIncidentFailure(id string, key string, start time.Time, response Response) error
+	AddIncidentAlert(id string, key string, msg string) error
+	SetIncidentCallout(id string, key string) error
+	ResolveIncident(id string, key string) error
+	SetIncidentSymptoms(id string, key string, symptoms []incidentSymptom) error
+	GetIncidents(filter IncidentFilter) ([]Incident, error)
+	GetOpenIncident(id string, key string) (Incident, error)
+	GetOpenIncidents(id string) ([]Incident, error)
 }
 
 func NewMongoStore(db *mgo.Database) Store {
+	ensureRetention(db)
 	return &store{
 		db: db,
 	}
@@ -78,6 +98,31 @@ func (s *store) SetCalloutInvoked(id string, key string) error {
 	return c.UpdateId(id+key, &r)
 }
 
+func (s *store) GetEscalationState(id string, key string) ([]escalationState, error) {
+	c := s.db.C("failurecound")
+	var r failurecount
+	err := c.FindId(id + key).One(&r)
+	return r.Escalation, err
+}
+
+func (s *store) SetEscalationStepFired(id string, key string, step int) error {
+	c := s.db.C("failurecound")
+	var r failurecount
+	err := c.FindId(id + key).One(&r)
+
+	if err != nil {
+		return err
+	}
+
+	for len(r.Escalation) <= step {
+		r.Escalation = append(r.Escalation, escalationState{})
+	}
+	r.Escalation[step].Fired = time.Now()
+	r.Escalation[step].Count++
+
+	return c.UpdateId(id+key, &r)
+}
+
 func (s *store) GetCount(id string, key string) (int, time.Time, error) {
 	c := s.db.C("failurecound")
 	var r failurecount
@@ -133,39 +178,176 @@ func (s *store) ZeroCount(id string, key string) error {
 		r.Count = 0
 		r.MessageSent = false
 		r.CalloutInvoked = false
+		r.Escalation = nil
 		return c.UpdateId(id+key, &r)
 	}
 }
 
-func (s *store) SaveRateData(d data) {
-	c := s.db.C("ratedata")
-	r := rateRecord{
-		Date:     time.Now(),
-		Approved: d.approved,
-		Declined: d.declined,
-		Failed:   d.failed,
+func (s *store) AddMaintenanceWindows(windows []MaintenanceWindow) error {
+	c := s.db.C("maintenance")
+	for _, w := range windows {
+		err := c.Insert(&w)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *store) GetMaintenanceWindows() (result []MaintenanceWindow, err error) {
+	err = s.db.C("maintenance").Find(nil).Sort("name").All(&result)
+	return
+}
+
+func (s *store) DeleteMaintenanceWindow(id string) error {
+	if !bson.IsObjectIdHex(id) {
+		return mgo.ErrNotFound
 	}
+	return s.db.C("maintenance").RemoveId(bson.ObjectIdHex(id))
+}
+
+func (s *store) ReplaceCalendar(calendar string, windows []MaintenanceWindow) error {
+	_, err := s.db.C("maintenance").RemoveAll(bson.M{"calendar": calendar})
+	if err != nil {
+		return err
+	}
+	return s.AddMaintenanceWindows(windows)
+}
+
+// openIncident selects the incident of the key that has not been resolved yet
+func openIncident(id string, key string) bson.M {
+	return bson.M{"monitor": id, "key": key, "resolved": nil}
+}
+
+/*
+RecordIncidentFailure counts the failure against the open incident, starting one if there isn't. The first message is
+kept as the peak message, unless it was a warning and a critical failure follows, which then replaces it.
+*/
+func (s *store) RecordIncidentFailure(id string, key string, start time.Time, response Response) error {
+	rank := severityRank(response.Severity)
+	c := s.db.C("incidents")
+	_, err := c.Upsert(openIncident(id, key), bson.M{
+		"$setOnInsert": bson.M{"_id": bson.NewObjectId(), "start": start, "peakmessage": response.FailureMsg, "peakseverity": rank},
+		"$set":         bson.M{"end": time.Now(), "lastmessage": response.FailureMsg},
+		"$inc":         bson.M{"failures": 1},
+	})
+	if err != nil {
+		return err
+	}
+
+	selector := openIncident(id, key)
+	selector["peakseverity"] = bson.M{"$lt": rank}
+	err = c.Update(selector, bson.M{"$set": bson.M{"peakmessage": response.FailureMsg, "peakseverity": rank}})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func (s *store) AddIncidentAlert(id string, key string, msg string) error {
+	return s.db.C("incidents").Update(openIncident(id, key), bson.M{
+		"$push": bson.M{"alerts": incidentAlert{Time: time.Now(), Message: msg}},
+	})
+}
+
+func (s *store) SetIncidentCallout(id string, key string) error {
+	return s.db.C("incidents").Update(openIncident(id, key), bson.M{"$set": bson.M{"callout": time.Now()}})
+}
+
+func (s *store) SetIncidentSymptoms(id string, key string, symptoms []incidentSymptom) error {
+	return s.db.C("incidents").Update(openIncident(id, key), bson.M{"$set": bson.M{"symptoms": symptoms}})
+}
+
+func (s *store) ResolveIncident(id string, key string) error {
+	err := s.db.C("incidents").Update(openIncident(id, key), bson.M{"$set": bson.M{"resolved": time.Now()}})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func (s *store) GetOpenIncident(id string, key string) (incident Incident, err error) {
+	err = s.db.C("incidents").Find(openIncident(id, key)).One(&incident)
+	return
+}
+
+func (s *store) GetOpenIncidents(id string) (result []Incident, err error) {
+	err = s.db.C("incidents").Find(bson.M{"monitor": id, "resolved": nil}).All(&result)
+	return
+}
+
+func (s *store) GetIncidents(filter IncidentFilter) (result []Incident, err error) {
+	q := bson.M{
+		"start": bson.M{"$lt": filter.To},
+		"$or":   []bson.M{{"resolved": nil}, {"resolved": bson.M{"$gte": filter.From}}},
+	}
+	if filter.Monitor != "" {
+		q["monitor"] = filter.Monitor
+	}
+	if filter.Key != "" {
+		q["key"] = filter.Key
+	}
+	err = s.db.C("incidents").Find(q).Sort("start").All(&result)
+	return
+}
+
+func (s *store) SaveRateData(r RateRecord) {
+	c := s.db.C("ratedata")
+	r.Date = time.Now()
 	c.Insert(&r)
 }
 
-func (s *store) SaveResponceCodeData(d []string) {
+func (s *store) SaveResponceCodeData(r []ResponseCodeRecord) {
 	c := s.db.C("responsecode")
-	r := responceCodeRecord{
-		Date:          time.Now(),
-		ResponseCodes: d,
+	for _, record := range r {
+		record.Date = time.Now()
+		c.Insert(&record)
 	}
+}
 
-	c.Insert(&r)
+func (s *store) GetRateData(monitor string, from, to time.Time) (result []RateRecord, err error) {
+	err = s.db.C("ratedata").Find(bson.M{"monitor": monitor, "date": bson.M{"$gte": from, "$lt": to}}).Sort("date").All(&result)
+	return
+}
+
+func (s *store) GetResponseCodeData(monitor string, from, to time.Time) (result []ResponseCodeRecord, err error) {
+	err = s.db.C("responsecode").Find(bson.M{"monitor": monitor, "date": bson.M{"$gte": from, "$lt": to}}).Sort("date").All(&result)
+	return
 }
 
-type rateRecord struct {
+func (s *store) SaveConnectionData(r []ConnectionRecord) {
+	c := s.db.C("connectiondata")
+	for _, record := range r {
+		record.Date = time.Now()
+		c.Insert(&record)
+	}
+}
+
+func (s *store) GetConnectionData(monitor string, from, to time.Time) (result []ConnectionRecord, err error) {
+	err = s.db.C("connectiondata").Find(bson.M{"monitor": monitor, "date": bson.M{"$gte": from, "$lt": to}}).Sort("date").All(&result)
+	return
+}
+
+// RateRecord is one row of a FailureRate widget. Id is the row id Prognosis gives it, which is the same while the row is updated
+type RateRecord struct {
 	Date                       time.Time
+	Monitor, Dashboard, Id     string
 	Failed, Approved, Declined int
 }
 
-type responceCodeRecord struct {
-	Date          time.Time
-	ResponseCodes []string
+// ResponseCodeRecord is the count of one response code in a row of a Code91 widget
+type ResponseCodeRecord struct {
+	Date                   time.Time
+	Monitor, Dashboard, Id string
+	Code                   string
+	Count                  int
+}
+
+// ConnectionRecord is the number of connections of one node on a SourceSink widget
+type ConnectionRecord struct {
+	Date                     time.Time
+	Monitor, Dashboard, Node string
+	Connections              int
 }
 
 type failurecount struct {
@@ -174,4 +356,11 @@ type failurecount struct {
 	FirstError     time.Time
 	MessageSent    bool
 	CalloutInvoked bool
+	//Escalation holds when each step of the escalation policy last fired, and how many times it has
+	Escalation []escalationState
+}
+
+type escalationState struct {
+	Fired time.Time
+	Count int
 }
@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/kyokomi/emoji"
+	"golang.org/x/net/context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sendDailySummaries posts the state of the switch to every group at the DailySummary time in the config
+func (s *service) sendDailySummaries(ctx context.Context) {
+	var clock string
+	var next time.Time
+	for {
+		if c := s.configuration().DailySummary; c != clock {
+			clock = c
+			next = nextClock(clock, time.Now())
+		}
+		if !next.IsZero() && !time.Now().Before(next) {
+			s.dailySummary(ctx, time.Date(next.Year(), next.Month(), next.Day(), 0, 0, 0, 0, next.Location()), time.Now())
+			next = nextClock(clock, time.Now())
+		}
+		time.Sleep(time.Minute)
+	}
+}
+
+// nextClock is the next time the clock, like 17:00, comes around. It is zero if the clock is empty or invalid
+func nextClock(clock string, now time.Time) time.Time {
+	if clock == "" {
+		return time.Time{}
+	}
+	minutes, err := parseClock(clock)
+	if err != nil {
+		log.Printf("Invalid DailySummary %v. %v", clock, err)
+		return time.Time{}
+	}
+	t := time.Date(now.Year(), now.Month(), now.Day(), minutes/60, minutes%60, 0, 0, now.Location())
+	if !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+/*
+dailySummary sends one message to each group, with a line for each of the group's monitors. FailureRate monitors give
+the transaction totals, Code91 monitors the count of codes 91 and 68, and SourceSink monitors the nodes that went down.
+*/
+func (s *service) dailySummary(ctx context.Context, from, to time.Time) {
+	var order []string
+	lines := map[string][]string{}
+	first := map[string]*monitors{}
+
+	for _, m := range s.configuration().Monitors {
+		if m.disabled() {
+			continue
+		}
+		line, err := s.summaryLine(m, from, to)
+		if err != nil {
+			log.Printf("Unable to summarise %v: %v", m.Name, err)
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		_, target := s.notifierFor(m, 0)
+		target = m.Notifier + target
+		if _, ok := first[target]; !ok {
+			first[target] = m
+			order = append(order, target)
+		}
+		lines[target] = append(lines[target], line)
+	}
+
+	for _, target := range order {
+		msg := emoji.Sprintf(":clipboard: State of the switch for %v\n", from.Format("Mon 2 Jan")) + strings.Join(lines[target], "\n")
+		s.alert(ctx, first[target], msg)
+	}
+}
+
+func (s *service) summaryLine(m *monitors, from, to time.Time) (string, error) {
+	switch m.Type {
+	case "FailureRate":
+		total, err := rateTotal(history{store: s.store, monitor: m.Name}, from, to)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("*%v*: %v approved, %v declined, %v failed", m.Name, total.approved, total.declined, total.failed), nil
+
+	case "Code91":
+		counts, err := codeTotals(history{store: s.store, monitor: m.Name}, from, to)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("*%v*: %v code 91, %v code 68", m.Name, counts["91"], counts["68"]), nil
+
+	case "SourceSink":
+		incidents, err := s.store.GetIncidents(IncidentFilter{From: from, To: to, Monitor: m.Name})
+		if err != nil {
+			return "", err
+		}
+		nodes := map[string][]Incident{}
+		for _, i := range s.downtime(incidents) {
+			nodes[i.Key] = append(nodes[i.Key], i)
+		}
+		if len(nodes) == 0 {
+			return fmt.Sprintf("*%v*: no nodes went down", m.Name), nil
+		}
+		var down []string
+		for node, list := range nodes {
+			down = append(down, fmt.Sprintf("%v down for %v", node, availability(list, from, to).Downtime))
+		}
+		sort.Strings(down)
+		return fmt.Sprintf("*%v*: %v", m.Name, strings.Join(down, ", ")), nil
+	}
+	return "", nil
+}
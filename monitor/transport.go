@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/weAutomateEverything/go2hal/gokit"
+	"net/http"
+)
+
+func MakeHandler(service Service, logger kitlog.Logger) http.Handler {
+	opts := gokit.GetServerOpts(logger, nil)
+
+	reloadConfig := kithttp.NewServer(makeReloadConfigEndpoint(service), decodeEmpty, gokit.EncodeResponse, opts...)
+	addMaintenance := kithttp.NewServer(makeAddMaintenanceWindowEndpoint(service), decodeMaintenanceWindow, gokit.EncodeResponse, opts...)
+	listMaintenance := kithttp.NewServer(makeListMaintenanceWindowsEndpoint(service), decodeEmpty, gokit.EncodeResponse, opts...)
+	deleteMaintenance := kithttp.NewServer(makeDeleteMaintenanceWindowEndpoint(service), decodeId, gokit.EncodeResponse, opts...)
+	importCalendar := kithttp.NewServer(makeImportCalendarEndpoint(service), decodeCalendar, gokit.EncodeResponse, opts...)
+	incidents := kithttp.NewServer(makeIncidentsEndpoint(service), decodeIncidents, encodeIncidents, opts...)
+	availability := kithttp.NewServer(makeAvailabilityEndpoint(service), decodeAvailability, gokit.EncodeResponse, opts...)
+	history := kithttp.NewServer(makeHistoryEndpoint(service), decodeHistory, gokit.EncodeResponse, opts...)
+	r := mux.NewRouter()
+
+	r.Handle("/monitor/config/reload", reloadConfig).Methods("POST")
+	r.Handle("/monitor/maintenance", addMaintenance).Methods("POST")
+	r.Handle("/monitor/maintenance", listMaintenance).Methods("GET")
+	r.Handle("/monitor/maintenance/calendar/{calendar}", importCalendar).Methods("PUT")
+	r.Handle("/monitor/maintenance/{id}", deleteMaintenance).Methods("DELETE")
+	r.Handle("/monitor/incidents", incidents).Methods("GET")
+	r.Handle("/reports/availability", availability).Methods("GET")
+	r.Handle("/monitor/history/{monitor}", history).Methods("GET")
+
+	return r
+}
+
+func decodeEmpty(_ context.Context, r *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+func decodeMaintenanceWindow(_ context.Context, r *http.Request) (interface{}, error) {
+	var w MaintenanceWindow
+	err := json.NewDecoder(r.Body).Decode(&w)
+	return w, err
+}
+
+func decodeId(_ context.Context, r *http.Request) (interface{}, error) {
+	return mux.Vars(r)["id"], nil
+}
+
+// decodeCalendar reads the iCal file from the body. The monitors and nodes it applies to are in the query string
+func decodeCalendar(_ context.Context, r *http.Request) (interface{}, error) {
+	return importCalendarRequest{
+		calendar: mux.Vars(r)["calendar"],
+		ics:      r.Body,
+		monitors: r.URL.Query()["monitor"],
+		nodes:    r.URL.Query()["node"],
+	}, nil
+}
+
+func decodeIncidents(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	from, err := parseFilterTime(q.Get("from"))
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseFilterTime(q.Get("to"))
+	if err != nil {
+		return nil, err
+	}
+	format := q.Get("format")
+	if format == "" && r.Header.Get("Accept") == "text/csv" {
+		format = "csv"
+	}
+	return incidentsRequest{
+		filter: IncidentFilter{From: from, To: to, Monitor: q.Get("monitor"), Key: q.Get("key")},
+		format: format,
+	}, nil
+}
+
+func encodeIncidents(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(incidentsResponse)
+	if resp.format != "csv" {
+		return gokit.EncodeResponse(ctx, w, resp.incidents)
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=incidents.csv")
+	return writeIncidentsCSV(w, resp.incidents)
+}
+
+func decodeAvailability(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	return availabilityRequest{period: q.Get("period"), monitor: q.Get("monitor")}, nil
+}
+
+func decodeHistory(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	from, err := parseFilterTime(q.Get("from"))
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseFilterTime(q.Get("to"))
+	if err != nil {
+		return nil, err
+	}
+	return historyRequest{monitor: mux.Vars(r)["monitor"], from: from, to: to}, nil
+}
@@ -0,0 +1,81 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+checkTrends compares the FailureRate with its recent past, to catch sudden changes that are still under the fixed
+percentages. A sharp drop in approvals is often the first sign of a problem at the acquirer.
+
+approvalDropPercent alerts when the approvals in the last trendWindow (15m by default) are that much lower than the
+baseline, which is the same time of day on the same day of the week, averaged over the last baselineWeeks weeks (1 by
+default). failureGrowth alerts when the failures in the last trendWindow are that many times the failures in the
+trendWindow before it, once there are at least minFailures (10 by default). The transactions of a period are what the
+rows counted in it, so a row that spans two periods is split between them.
+*/
+func (s failureRateMonitor) checkTrends(check Check, now time.Time) (response []Response, err error) {
+	if check.Params.Float("approvalDropPercent", 0) <= 0 && check.Params.Float("failureGrowth", 0) <= 0 {
+		return
+	}
+	window := check.Params.Duration("trendWindow", 15*time.Minute)
+	minVolume := check.Params.Int("minVolume", 0)
+
+	current, err := rateTotal(check.History, now.Add(-window), now)
+	if err != nil {
+		return
+	}
+
+	if limit := check.Params.Float("approvalDropPercent", 0); limit > 0 {
+		resp := Response{Key: "Approvals"}
+		weeks := check.Params.Int("baselineWeeks", 1)
+		var baseline float64
+		var found int
+		for i := 1; i <= weeks; i++ {
+			then := now.AddDate(0, 0, -7*i)
+			total, err := rateTotal(check.History, then.Add(-window), then)
+			if err != nil {
+				return nil, err
+			}
+			if total.volume() == 0 {
+				continue
+			}
+			baseline += float64(total.approved)
+			found++
+		}
+		if found > 0 {
+			baseline /= float64(found)
+		}
+		if baseline > 0 && int(baseline) >= minVolume {
+			drop := 100 * (baseline - float64(current.approved)) / baseline
+			if drop > limit {
+				resp.Failure = true
+				resp.FailureMsg = fmt.Sprintf("Approvals dropped %.1f%% compared with the same time over the last %v weeks. %v approved in the last %v, against %.0f on average",
+					drop, found, current.approved, window, baseline)
+			}
+		}
+		response = append(response, resp)
+	}
+
+	if growth := check.Params.Float("failureGrowth", 0); growth > 0 {
+		resp := Response{Key: "FailureTrend"}
+		previous, err := rateTotal(check.History, now.Add(-2*window), now.Add(-window))
+		if err != nil {
+			return nil, err
+		}
+		if current.failed >= check.Params.Int("minFailures", 10) && current.volume() >= minVolume {
+			before := previous.failed
+			if before == 0 {
+				before = 1
+			}
+			if factor := float64(current.failed) / float64(before); factor >= growth {
+				resp.Failure = true
+				resp.FailureMsg = fmt.Sprintf("Failures grew %.1f times in the last %v. %v failed, against %v in the %v before",
+					factor, window, current.failed, previous.failed, window)
+			}
+		}
+		response = append(response, resp)
+	}
+	return
+}
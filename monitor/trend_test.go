@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckTrends(t *testing.T) {
+	week := 7 * 24 * time.Hour
+	tests := []struct {
+		name    string
+		params  Params
+		history []RateRecord
+		key     string
+		failure bool
+	}{
+		{
+			name:   "failures grew",
+			params: Params{"failureGrowth": float64(2), "minFailures": float64(5)},
+			history: []RateRecord{
+				sample(25*time.Minute, "a", 100, 0, 2),
+				sample(5*time.Minute, "a", 150, 0, 30),
+			},
+			key:     "FailureTrend",
+			failure: true,
+		},
+		{
+			//The row failed 10 times in each window. Its running total doubled, but the failures did not grow
+			name:   "steady failures on a row that spans both windows",
+			params: Params{"failureGrowth": float64(2), "minFailures": float64(5)},
+			history: []RateRecord{
+				sample(40*time.Minute, "a", 100, 0, 0),
+				sample(20*time.Minute, "a", 200, 0, 10),
+				sample(5*time.Minute, "a", 300, 0, 20),
+			},
+			key: "FailureTrend",
+		},
+		{
+			name:   "under the minimum failures",
+			params: Params{"failureGrowth": float64(2), "minFailures": float64(50)},
+			history: []RateRecord{
+				sample(5*time.Minute, "a", 150, 0, 30),
+			},
+			key: "FailureTrend",
+		},
+		{
+			name:   "approvals dropped",
+			params: Params{"approvalDropPercent": float64(50)},
+			history: []RateRecord{
+				sample(week+5*time.Minute, "a", 100, 0, 0),
+				sample(5*time.Minute, "b", 20, 0, 0),
+			},
+			key:     "Approvals",
+			failure: true,
+		},
+		{
+			name:   "approvals as usual",
+			params: Params{"approvalDropPercent": float64(50)},
+			history: []RateRecord{
+				sample(week+5*time.Minute, "a", 100, 0, 0),
+				sample(5*time.Minute, "b", 90, 0, 0),
+			},
+			key: "Approvals",
+		},
+		{
+			name:   "no baseline",
+			params: Params{"approvalDropPercent": float64(50)},
+			history: []RateRecord{
+				sample(5*time.Minute, "b", 0, 0, 10),
+			},
+			key: "Approvals",
+		},
+	}
+
+	t.Run("nothing to check", func(t *testing.T) {
+		s := NewFailureRateMonitor().(*failureRateMonitor)
+		response, err := s.checkTrends(testCheck(nil, nil), time.Now())
+		if err != nil || response != nil {
+			t.Errorf("checkTrends() = %+v, %v, want nothing", response, err)
+		}
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewFailureRateMonitor().(*failureRateMonitor)
+			response, err := s.checkTrends(testCheck(tt.params, &fakeHistory{rates: tt.history}), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(response) != 1 || response[0].Key != tt.key {
+				t.Fatalf("checkTrends() = %+v, want one %v response", response, tt.key)
+			}
+			if response[0].Failure != tt.failure {
+				t.Errorf("failure = %v, want %v. %v", response[0].Failure, tt.failure, response[0].FailureMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package monitor
+
+import (
+	"fmt"
+	"github.com/weAutomateEverything/prognosisHalBot/notifier"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConfigError is a problem with the config, together with the JSON path of the field that has the problem
+type ConfigError struct {
+	Path    string
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Path, e.Message)
+}
+
+// ConfigErrors is every problem found in a config
+type ConfigErrors []ConfigError
+
+func (e ConfigErrors) Error() string {
+	var lines []string
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	if len(e) == 1 {
+		return fmt.Sprintf("1 problem found in the config:\n%v", lines[0])
+	}
+	return fmt.Sprintf("%v problems found in the config:\n%v", len(e), strings.Join(lines, "\n"))
+}
+
+/*
+ValidateConfig loads the config from the source, which can be anything CONFIG_URL accepts, and checks it against the
+monitor implementations. It is what the validate-config command runs, so a config can be checked before it is merged.
+*/
+func ValidateConfig(source string, notifiers map[string]notifier.Notifier, checks ...Monitor) error {
+	c, err := loadConfig(source)
+	if err != nil {
+		return err
+	}
+	types := map[string]Monitor{}
+	for _, m := range checks {
+		types[m.GetName()] = m
+	}
+	return validate(c, types, notifiers)
+}
+
+func (s *service) validateConfig(c environment) error {
+	return validate(c, s.monitors, s.notifiers)
+}
+
+func validate(c environment, types map[string]Monitor, notifiers map[string]notifier.Notifier) error {
+	var errs ConfigErrors
+	add := func(path, format string, args ...interface{}) {
+		errs = append(errs, ConfigError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(c.Address) == 0 {
+		add("$.Address", "at least one Prognosis address is required")
+	}
+	for i, address := range c.Address {
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add(fmt.Sprintf("$.Address[%v]", i), "%q is not a http or https address", address)
+		}
+	}
+
+	if c.DailySummary != "" {
+		if _, err := parseClock(c.DailySummary); err != nil {
+			add("$.DailySummary", "%v", err)
+		}
+	}
+
+	var known []string
+	for t := range types {
+		known = append(known, t)
+	}
+	sort.Strings(known)
+
+	names := map[string]int{}
+	for i, m := range c.Monitors {
+		path := fmt.Sprintf("$.Monitors[%v]", i)
+		if m == nil {
+			add(path, "is empty")
+			continue
+		}
+
+		if m.Name == "" {
+			add(path+".Name", "is required")
+		} else if j, ok := names[m.Name]; ok {
+			add(path+".Name", "%q is already used by $.Monitors[%v]", m.Name, j)
+		} else {
+			names[m.Name] = i
+		}
+
+		if t, ok := types[m.Type]; !ok {
+			add(path+".Type", "unknown monitor type %q, expected one of %v", m.Type, strings.Join(known, ", "))
+		} else if v, ok := t.(ParamsValidator); ok {
+			if err := v.ValidateParams(m.Params); err != nil {
+				add(path+".Params", "%v", err)
+			}
+		}
+		if _, ok := types[m.Type].(SiteMonitor); ok {
+			if len(m.Sites) < 2 {
+				add(path+".Sites", "%v monitors compare at least 2 sites", m.Type)
+			}
+			for j, site := range m.Sites {
+				sitePath := fmt.Sprintf("%v.Sites[%v]", path, j)
+				if site.Name == "" {
+					add(sitePath+".Name", "is required")
+				}
+				if site.Dashboard == "" {
+					add(sitePath+".Dashboard", "is required")
+				}
+				if site.Id == "" {
+					add(sitePath+".Id", "is required")
+				}
+			}
+		} else {
+			if m.Dashboard == "" {
+				add(path+".Dashboard", "is required")
+			}
+			if m.Id == "" {
+				add(path+".Id", "is required")
+			}
+		}
+		if m.Group == 0 && m.Target == "" {
+			add(path+".Group", "is required")
+		}
+		validateEscalation(path, m.Escalation, add)
+		if m.ReminderBackoff != 0 && m.ReminderBackoff < 1 {
+			add(path+".ReminderBackoff", "%v would make reminders more frequent, use 1 or more", m.ReminderBackoff)
+		}
+		switch m.Mode {
+		case "", modeLive, modeShadow, modeDisabled:
+		default:
+			add(path+".Mode", "unknown mode %q, expected one of live, shadow, disabled", m.Mode)
+		}
+		if m.Notifier != "" {
+			if _, ok := notifiers[m.Notifier]; !ok {
+				add(path+".Notifier", "unknown notifier %q, expected one of %v", m.Notifier, strings.Join(sortedKeys(notifiers), ", "))
+			}
+		}
+		if m.Interval != "" {
+			if _, err := parseInterval(m.Interval); err != nil {
+				add(path+".Interval", "%v", err)
+			}
+		}
+		if m.Schedule != "" {
+			if _, err := newSchedule(&monitors{Schedule: m.Schedule}); err != nil {
+				add(path+".Schedule", "%v", err)
+			}
+		}
+	}
+
+	rules := map[string]int{}
+	correlated := map[string]int{}
+	for i, rule := range c.Correlations {
+		path := fmt.Sprintf("$.Correlations[%v]", i)
+		if rule.Name == "" {
+			add(path+".Name", "is required")
+		} else if j, ok := rules[rule.Name]; ok {
+			add(path+".Name", "%q is already used by $.Correlations[%v]", rule.Name, j)
+		} else if j, ok := names[rule.Name]; ok {
+			add(path+".Name", "%q is already used by $.Monitors[%v]", rule.Name, j)
+		} else {
+			rules[rule.Name] = i
+		}
+		if len(rule.Monitors) < 2 {
+			add(path+".Monitors", "a correlation needs at least 2 monitors")
+		}
+		for j, name := range rule.Monitors {
+			if _, ok := names[name]; !ok {
+				add(fmt.Sprintf("%v.Monitors[%v]", path, j), "there is no monitor called %q", name)
+			} else if k, ok := correlated[name]; ok && k != i {
+				add(fmt.Sprintf("%v.Monitors[%v]", path, j), "%q is already in $.Correlations[%v]", name, k)
+			} else {
+				correlated[name] = i
+			}
+		}
+		if rule.Window != "" {
+			if _, err := time.ParseDuration(rule.Window); err != nil {
+				add(path+".Window", "%q is not a duration, like 30s or 5m", rule.Window)
+			}
+		}
+		if rule.Notifier != "" {
+			if _, ok := notifiers[rule.Notifier]; !ok {
+				add(path+".Notifier", "unknown notifier %q, expected one of %v", rule.Notifier, strings.Join(sortedKeys(notifiers), ", "))
+			}
+		}
+		validateEscalation(path, rule.Escalation, add)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func sortedKeys(notifiers map[string]notifier.Notifier) (keys []string) {
+	for key := range notifiers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func validateEscalation(path string, steps []escalationStep, add func(path, format string, args ...interface{})) {
+	var previous time.Duration
+	for i, step := range steps {
+		stepPath := fmt.Sprintf("%v.Escalation[%v]", path, i)
+		d, err := time.ParseDuration(step.Delay)
+		if err != nil {
+			add(stepPath+".Delay", "%q is not a duration, like 30s or 5m", step.Delay)
+		} else if d < previous {
+			add(stepPath+".Delay", "%v is before the delay of the previous step, steps must be in order", step.Delay)
+		} else {
+			previous = d
+		}
+		if step.Action != actionAlert && step.Action != actionCallout {
+			add(stepPath+".Action", "unknown action %q, expected alert or callout", step.Action)
+		}
+		if step.Repeat != "" {
+			if _, err := time.ParseDuration(step.Repeat); err != nil {
+				add(stepPath+".Repeat", "%q is not a duration, like 30s or 5m", step.Repeat)
+			}
+		}
+	}
+}
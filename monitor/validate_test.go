@@ -0,0 +1,135 @@
+package monitor
+
+import (
+	"github.com/weAutomateEverything/prognosisHalBot/notifier"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	types := map[string]Monitor{}
+	for _, m := range []Monitor{NewFailureRateMonitor(), NewResponseCode91Monitor(), NewCrossSiteMonitor()} {
+		types[m.GetName()] = m
+	}
+	base := func() environment {
+		return environment{
+			Address: []string{"https://prognosis"},
+			Monitors: []*monitors{
+				{Name: "Rate", Type: "FailureRate", Dashboard: "D", Id: "1", Group: 1, Interval: "1m"},
+				{Name: "Codes", Type: "Code91", Dashboard: "D", Id: "2", Group: 1, Schedule: "Mon-Fri 06:00-22:00",
+					Escalation: []escalationStep{{Delay: "0s", Action: "alert"}, {Delay: "10m", Action: "callout"}}},
+			},
+			Correlations: []correlation{{Name: "Switch", Monitors: []string{"Rate", "Codes"}}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *environment)
+		paths  []string
+	}{
+		{
+			name:   "valid",
+			change: func(c *environment) {},
+		},
+		{
+			name:   "no address",
+			change: func(c *environment) { c.Address = nil },
+			paths:  []string{"$.Address"},
+		},
+		{
+			name:   "not a http address",
+			change: func(c *environment) { c.Address = []string{"https://prognosis", "prognosis:8080"} },
+			paths:  []string{"$.Address[1]"},
+		},
+		{
+			name:   "daily summary",
+			change: func(c *environment) { c.DailySummary = "5pm" },
+			paths:  []string{"$.DailySummary"},
+		},
+		{
+			name:   "duplicate name",
+			change: func(c *environment) { c.Monitors[1].Name = "Rate" },
+			paths:  []string{"$.Monitors[1].Name", "$.Correlations[0].Monitors[1]"},
+		},
+		{
+			name:   "unknown type",
+			change: func(c *environment) { c.Monitors[0].Type = "Magic" },
+			paths:  []string{"$.Monitors[0].Type"},
+		},
+		{
+			name:   "missing fields",
+			change: func(c *environment) { c.Monitors[0].Dashboard, c.Monitors[0].Id, c.Monitors[0].Group = "", "", 0 },
+			paths:  []string{"$.Monitors[0].Dashboard", "$.Monitors[0].Id", "$.Monitors[0].Group"},
+		},
+		{
+			name: "params",
+			change: func(c *environment) {
+				c.Monitors[1].Params = Params{"codes": []interface{}{map[string]interface{}{"code": "91"}}}
+			},
+			paths: []string{"$.Monitors[1].Params"},
+		},
+		{
+			name:   "cross site needs sites",
+			change: func(c *environment) { c.Monitors[0].Type = "CrossSite" },
+			paths:  []string{"$.Monitors[0].Sites"},
+		},
+		{
+			name: "escalation out of order",
+			change: func(c *environment) {
+				c.Monitors[1].Escalation = []escalationStep{{Delay: "10m", Action: "alert"}, {Delay: "5m", Action: "page"}}
+			},
+			paths: []string{"$.Monitors[1].Escalation[1].Delay", "$.Monitors[1].Escalation[1].Action"},
+		},
+		{
+			name:   "reminder backoff",
+			change: func(c *environment) { c.Monitors[0].ReminderBackoff = 0.5 },
+			paths:  []string{"$.Monitors[0].ReminderBackoff"},
+		},
+		{
+			name:   "mode",
+			change: func(c *environment) { c.Monitors[0].Mode = "quiet" },
+			paths:  []string{"$.Monitors[0].Mode"},
+		},
+		{
+			name:   "notifier",
+			change: func(c *environment) { c.Monitors[0].Notifier = "pager" },
+			paths:  []string{"$.Monitors[0].Notifier"},
+		},
+		{
+			name:   "interval and schedule",
+			change: func(c *environment) { c.Monitors[0].Interval, c.Monitors[1].Schedule = "often", "Mon-Fri" },
+			paths:  []string{"$.Monitors[0].Interval", "$.Monitors[1].Schedule"},
+		},
+		{
+			name:   "interval of 0",
+			change: func(c *environment) { c.Monitors[0].Interval = "0s" },
+			paths:  []string{"$.Monitors[0].Interval"},
+		},
+		{
+			name: "correlation",
+			change: func(c *environment) {
+				c.Correlations = append(c.Correlations, correlation{Name: "Rate", Monitors: []string{"Codes", "Missing"}, Window: "soon"})
+			},
+			paths: []string{"$.Correlations[1].Name", "$.Correlations[1].Monitors[0]", "$.Correlations[1].Monitors[1]",
+				"$.Correlations[1].Window"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := base()
+			tt.change(&c)
+			err := validate(c, types, map[string]notifier.Notifier{})
+			var paths []string
+			if err != nil {
+				for _, e := range err.(ConfigErrors) {
+					paths = append(paths, e.Path)
+				}
+			}
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("validate() = %v, want errors at %v", err, tt.paths)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/weAutomateEverything/go2hal/callout"
+	"golang.org/x/net/context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NewHALNotifier sends alerts and callouts to the HAL group with the id in target
+func NewHALNotifier(endpoint string) Notifier {
+	return &halNotifier{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type halNotifier struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (n halNotifier) Alert(ctx context.Context, target string, msg string) error {
+	//HAL sends the message as markdown, where underscores start italics
+	msg = strings.Replace(msg, "_", " ", -1)
+	return retry(ctx, func() error {
+		return n.post(ctx, fmt.Sprintf("%v/api/alert/%v", n.endpoint, target), "application/text", strings.NewReader(msg))
+	})
+}
+
+func (n halNotifier) Callout(ctx context.Context, target string, title string, msg string) error {
+	b, err := json.Marshal(callout.SendCalloutRequest{
+		Message: msg,
+		Title:   title,
+	})
+	if err != nil {
+		return err
+	}
+	return retry(ctx, func() error {
+		return n.post(ctx, fmt.Sprintf("%v/api/callout/%v", n.endpoint, target), "application/json", bytes.NewReader(b))
+	})
+}
+
+func (n halNotifier) post(ctx context.Context, url, contentType string, body io.ReadSeeker) error {
+	body.Seek(0, io.SeekStart)
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := n.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
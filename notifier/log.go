@@ -0,0 +1,24 @@
+package notifier
+
+import (
+	"golang.org/x/net/context"
+	"log"
+)
+
+// NewLogNotifier only logs the alerts and callouts. Use it to dry run a monitor.
+func NewLogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+type logNotifier struct {
+}
+
+func (logNotifier) Alert(ctx context.Context, target string, msg string) error {
+	log.Printf("[DRY RUN] alert for %v: %v", target, msg)
+	return nil
+}
+
+func (logNotifier) Callout(ctx context.Context, target string, title string, msg string) error {
+	log.Printf("[DRY RUN] callout for %v: %v - %v", target, title, msg)
+	return nil
+}
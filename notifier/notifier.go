@@ -0,0 +1,77 @@
+package notifier
+
+import (
+	"fmt"
+	"golang.org/x/net/context"
+	"log"
+	"os"
+	"time"
+)
+
+// Names the notifiers are selected by in the monitor config
+const (
+	HAL     = "hal"
+	Webhook = "webhook"
+	SMTP    = "smtp"
+	Log     = "log"
+)
+
+/*
+Notifier sends alerts and callouts somewhere people will see them. What target means depends on the notifier - a
+HAL group id, a webhook url or an email address.
+*/
+type Notifier interface {
+	Alert(ctx context.Context, target string, msg string) error
+	Callout(ctx context.Context, target string, title string, msg string) error
+}
+
+// FromEnvironment creates every notifier, configured from the environment, keyed by name
+func FromEnvironment() map[string]Notifier {
+	return map[string]Notifier{
+		HAL:     NewHALNotifier(os.Getenv("HAL_ENDPOINT")),
+		Webhook: NewWebhookNotifier(os.Getenv("WEBHOOK_URL")),
+		SMTP:    NewSMTPNotifier(os.Getenv("SMTP_SERVER"), os.Getenv("SMTP_FROM_ADDRESS")),
+		Log:     NewLogNotifier(),
+	}
+}
+
+// StatusError is returned when the notification is rejected with a non 2xx status
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %v from %v", e.StatusCode, e.URL)
+}
+
+func (e StatusError) permanent() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
+/*
+retry calls send up to 3 times, waiting 1 and then 2 seconds between the attempts. A 4xx response will not get better by
+trying again, so it is returned straight away.
+*/
+func retry(ctx context.Context, send func() error) (err error) {
+	wait := time.Second
+	for attempt := 1; ; attempt++ {
+		err = send()
+		if err == nil {
+			return
+		}
+		if e, ok := err.(StatusError); ok && e.permanent() {
+			return
+		}
+		if attempt == 3 {
+			return
+		}
+		log.Printf("Notification failed, trying again in %v. %v", wait, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
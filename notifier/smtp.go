@@ -0,0 +1,50 @@
+package notifier
+
+import (
+	"fmt"
+	"golang.org/x/net/context"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// NewSMTPNotifier emails the alerts and callouts to the comma separated addresses in target
+func NewSMTPNotifier(server, from string) Notifier {
+	if server != "" {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "25")
+		}
+	}
+	return &smtpNotifier{
+		server: server,
+		from:   from,
+	}
+}
+
+type smtpNotifier struct {
+	server, from string
+}
+
+func (n smtpNotifier) Alert(ctx context.Context, target string, msg string) error {
+	return n.send(ctx, target, "Prognosis alert", msg)
+}
+
+func (n smtpNotifier) Callout(ctx context.Context, target string, title string, msg string) error {
+	return n.send(ctx, target, "CALLOUT: "+title, msg)
+}
+
+func (n smtpNotifier) send(ctx context.Context, target, subject, msg string) error {
+	if n.server == "" {
+		return fmt.Errorf("SMTP_SERVER is not set, unable to email %v", target)
+	}
+
+	var to []string
+	for _, address := range strings.Split(target, ",") {
+		to = append(to, strings.TrimSpace(address))
+	}
+
+	body := fmt.Sprintf("From: %v\r\nTo: %v\r\nSubject: %v\r\n\r\n%v\r\n", n.from, strings.Join(to, ", "), subject, msg)
+	return retry(ctx, func() error {
+		return smtp.SendMail(n.server, nil, n.from, to, []byte(body))
+	})
+}
@@ -0,0 +1,72 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"golang.org/x/net/context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*
+NewWebhookNotifier posts the alerts and callouts as JSON. If the target is a url the notification is posted there,
+otherwise it is posted to the default url with the target in the body.
+*/
+func NewWebhookNotifier(url string) Notifier {
+	return &webhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+type webhookRequest struct {
+	Type    string `json:"type"`
+	Target  string `json:"target"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+}
+
+func (n webhookNotifier) Alert(ctx context.Context, target string, msg string) error {
+	return n.send(ctx, webhookRequest{Type: "alert", Target: target, Message: msg})
+}
+
+func (n webhookNotifier) Callout(ctx context.Context, target string, title string, msg string) error {
+	return n.send(ctx, webhookRequest{Type: "callout", Target: target, Title: title, Message: msg})
+}
+
+func (n webhookNotifier) send(ctx context.Context, r webhookRequest) error {
+	url := n.url
+	if strings.HasPrefix(r.Target, "http://") || strings.HasPrefix(r.Target, "https://") {
+		url = r.Target
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return retry(ctx, func() error {
+		req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := n.client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return StatusError{URL: url, StatusCode: resp.StatusCode}
+		}
+		return nil
+	})
+}
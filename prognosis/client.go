@@ -0,0 +1,186 @@
+package prognosis
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"golang.org/x/net/context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/*
+Client talks to a single Prognosis web server. Every client has its own http.Client and cookie jar, so the session
+obtained by Login is reused by the calls that follow it without touching http.DefaultClient.
+*/
+type Client interface {
+	// Login posts the credentials to the Prognosis login page and keeps the session cookie in the clients cookie jar
+	Login(ctx context.Context) error
+
+	// ResolveWidgetGUID finds the GUID of the widget with the html id on the dashboard. The GUID changes whenever
+	// Prognosis restarts, so it needs to be looked up before the view is fetched
+	ResolveWidgetGUID(ctx context.Context, dashboard, id string) (string, error)
+
+	// FetchDashboardView downloads the data for the widget with the GUID
+	FetchDashboardView(ctx context.Context, guid string) (DashboardView, error)
+
+	// Address returns the base address of the Prognosis server, for example https://196.8.10.103
+	Address() string
+}
+
+func NewClient(address, username, password string) Client {
+	jar, _ := cookiejar.New(nil)
+	return &client{
+		address:  strings.TrimSuffix(address, "/"),
+		username: username,
+		password: password,
+		http: &http.Client{
+			Jar:     jar,
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+type client struct {
+	address            string
+	username, password string
+	http               *http.Client
+}
+
+func (c *client) Address() string {
+	return c.address
+}
+
+func (c *client) Login(ctx context.Context) error {
+	log.Printf("Logging in to %v", c.address)
+	v := url.Values{}
+	v.Add("UserName", c.username)
+	v.Add("Password", c.password)
+	v.Add("Destination", "View Systems")
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%v/Prognosis/Login?returnUrl=/Prognosis/", c.address), strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if len(resp.Cookies()) == 0 {
+		return ErrNoCookie
+	}
+	return nil
+}
+
+func (c *client) ResolveWidgetGUID(ctx context.Context, dashboard, id string) (guid string, err error) {
+	body, err := c.get(ctx, fmt.Sprintf("%v/Prognosis/Dashboard/Content/%v", c.address, dashboard))
+	if err != nil {
+		return
+	}
+
+	doc, err := htmlquery.Parse(bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+
+	nodes := htmlquery.Find(doc, fmt.Sprintf("//div[@id='%v']", id))
+	for _, node := range nodes {
+		child := node.FirstChild
+		for child != nil && child.Data != "script" {
+			child = child.NextSibling
+		}
+		if child == nil || child.FirstChild == nil {
+			continue
+		}
+		lines := strings.Split(child.FirstChild.Data, "\n")
+		for _, line := range lines {
+			if strings.Index(line, "guid") != -1 {
+				guid = line[strings.Index(line, "guid")+5:]
+				guid = strings.Replace(guid, "\"", "", -1)
+				guid = strings.Replace(guid, ",", "", -1)
+				guid = strings.TrimSpace(guid)
+				return guid, nil
+			}
+		}
+	}
+	return "", GUIDNotFoundError{Dashboard: dashboard, Id: id}
+}
+
+func (c *client) FetchDashboardView(ctx context.Context, guid string) (view DashboardView, err error) {
+	body, err := c.get(ctx, fmt.Sprintf("%v/Prognosis/DashboardView/%v", c.address, guid))
+	if err != nil {
+		return
+	}
+
+	view.GUID = guid
+	err = json.Unmarshal(body, &view.widgets)
+	if err != nil {
+		return
+	}
+	if view.widgets == nil {
+		err = ErrNoData
+	}
+	return
+}
+
+/*
+get fetches the url with the session cookie and returns the body. Prognosis does not use a single way of telling us the
+session is no longer valid - depending on the page it redirects to the login page, serves the login page in place of the
+content or responds with a 401. All of them are returned as ErrSessionExpired.
+*/
+func (c *client) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return nil, ErrSessionExpired
+	case http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect:
+		if strings.Contains(strings.ToLower(resp.Header.Get("Location")), "/prognosis/login") {
+			return nil, ErrSessionExpired
+		}
+		return nil, StatusError{URL: url, StatusCode: resp.StatusCode}
+	default:
+		return nil, StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if isLoginPage(body) {
+		return nil, ErrSessionExpired
+	}
+	return body, nil
+}
+
+func isLoginPage(body []byte) bool {
+	b := bytes.ToLower(body)
+	return bytes.Contains(b, []byte("/prognosis/login")) && bytes.Contains(b, []byte(`name="password"`))
+}
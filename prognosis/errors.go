@@ -0,0 +1,43 @@
+package prognosis
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoCookie is returned by Login when Prognosis does not hand out a session cookie
+var ErrNoCookie = errors.New("prognosis: no cookie found on login response")
+
+// ErrNoData is returned when Prognosis has not populated the widget yet. It usually does after a couple of requests
+var ErrNoData = errors.New("prognosis: no data found")
+
+// ErrSessionExpired is returned when Prognosis no longer accepts the session cookie. Logging in again fixes it
+var ErrSessionExpired = errors.New("prognosis: session expired")
+
+// GUIDNotFoundError is returned when the widget can not be found on the dashboard
+type GUIDNotFoundError struct {
+	Dashboard, Id string
+}
+
+func (e GUIDNotFoundError) Error() string {
+	return fmt.Sprintf("prognosis: no guid found for %v on dashboard %v", e.Id, e.Dashboard)
+}
+
+// WidgetNotFoundError is returned when the dashboard view does not contain the widget
+type WidgetNotFoundError struct {
+	GUID, Id string
+}
+
+func (e WidgetNotFoundError) Error() string {
+	return fmt.Sprintf("prognosis: widget %v not found in view %v", e.Id, e.GUID)
+}
+
+// StatusError is returned when Prognosis responds with anything other than a 200
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("prognosis: unexpected status %v from %v", e.StatusCode, e.URL)
+}
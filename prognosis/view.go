@@ -0,0 +1,57 @@
+package prognosis
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// DashboardView is the decoded response of a DashboardView request, keyed by widget id
+type DashboardView struct {
+	GUID    string
+	widgets map[string]json.RawMessage
+}
+
+type widget struct {
+	Data []json.RawMessage
+}
+
+/*
+Rows returns the table rows of the widget with the html id. Prognosis prefixes every table with two header rows which
+are dropped, so a widget that only contains the headers returns no rows and no error.
+*/
+func (v DashboardView) Rows(id string) (rows [][]string, err error) {
+	raw, ok := v.widgets[strings.TrimPrefix(id, "id_")]
+	if !ok {
+		return nil, WidgetNotFoundError{GUID: v.GUID, Id: id}
+	}
+
+	var w widget
+	err = json.Unmarshal(raw, &w)
+	if err != nil {
+		return
+	}
+
+	if len(w.Data) == 0 {
+		return nil, ErrNoData
+	}
+
+	var elements []json.RawMessage
+	err = json.Unmarshal(w.Data[0], &elements)
+	if err != nil {
+		return
+	}
+
+	if len(elements) <= 2 {
+		return
+	}
+
+	for _, element := range elements[2:] {
+		var row []string
+		err = json.Unmarshal(element, &row)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return
+}
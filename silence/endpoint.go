@@ -0,0 +1,38 @@
+package silence
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	"time"
+)
+
+type createSilenceRequest struct {
+	Monitor  string `json:"monitor"`
+	Key      string `json:"key"`
+	Duration string `json:"duration"`
+	Reason   string `json:"reason"`
+}
+
+func makeCreateSilenceEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(createSilenceRequest)
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			return
+		}
+		return s.Create(ctx, req.Monitor, req.Key, d, req.Reason)
+	}
+}
+
+func makeListSilencesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		return s.List(ctx)
+	}
+}
+
+func makeDeleteSilenceEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		err = s.Delete(ctx, request.(string))
+		return
+	}
+}
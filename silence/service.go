@@ -0,0 +1,119 @@
+package silence
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kyokomi/emoji"
+	"golang.org/x/net/context"
+	"log"
+	"path"
+	"time"
+)
+
+// ErrNotActive is returned when a silence is deleted that does not exist or has already ended
+var ErrNotActive = errors.New("there is no active silence with that id")
+
+// Announcer posts a message to the groups of the monitors matching the pattern
+type Announcer interface {
+	Announce(ctx context.Context, monitor string, msg string)
+}
+
+type Service interface {
+	Create(ctx context.Context, monitor, key string, duration time.Duration, reason string) (Silence, error)
+	List(ctx context.Context) ([]Silence, error)
+	Delete(ctx context.Context, id string) error
+}
+
+func NewService(store Store, announcer Announcer) Service {
+	s := &service{
+		store:     store,
+		announcer: announcer,
+	}
+	go s.announceEnded()
+	return s
+}
+
+type service struct {
+	store     Store
+	announcer Announcer
+}
+
+func (s *service) Create(ctx context.Context, monitor, key string, duration time.Duration, reason string) (silence Silence, err error) {
+	if duration <= 0 {
+		return silence, errors.New("the duration of a silence has to be more than 0")
+	}
+	if reason == "" {
+		return silence, errors.New("a reason is required")
+	}
+	for _, pattern := range []string{monitor, key} {
+		if _, err = path.Match(pattern, ""); err != nil {
+			return silence, fmt.Errorf("%v is not a valid pattern. %v", pattern, err)
+		}
+	}
+
+	now := time.Now()
+	silence = Silence{
+		Monitor: monitor,
+		Key:     key,
+		Reason:  reason,
+		Start:   now,
+		End:     now.Add(duration),
+	}
+	err = s.store.addSilence(&silence)
+	if err != nil {
+		return
+	}
+
+	s.announcer.Announce(ctx, monitor, emoji.Sprintf(":mute: Alerts for %v are silenced until %v. %v",
+		describe(silence), silence.End.Format("2006-01-02 15:04"), reason))
+	return
+}
+
+func (s *service) List(ctx context.Context) ([]Silence, error) {
+	return s.store.getActive()
+}
+
+// Delete ends an active silence. Silences that have already ended can not be deleted, and return ErrNotActive
+func (s *service) Delete(ctx context.Context, id string) error {
+	silence, err := s.store.endSilence(id, time.Now())
+	if err != nil {
+		return err
+	}
+	return s.end(ctx, silence)
+}
+
+// announceEnded lets the groups know when a silence runs out, or was removed
+func (s *service) announceEnded() {
+	for {
+		time.Sleep(time.Minute)
+		ended, err := s.store.getEnded()
+		if err != nil {
+			log.Printf("Unable to check for ended silences: %v", err)
+			continue
+		}
+		for _, silence := range ended {
+			s.end(context.Background(), silence)
+		}
+	}
+}
+
+// end announces that the silence ended. Delete and announceEnded can both see the same silence end, only the one that claims it announces it
+func (s *service) end(ctx context.Context, silence Silence) error {
+	claimed, err := s.store.claimAnnouncement(silence.ID.Hex())
+	if err != nil || !claimed {
+		return err
+	}
+	s.announcer.Announce(ctx, silence.Monitor, emoji.Sprintf(":sound: Alerts for %v are no longer silenced.", describe(silence)))
+	return nil
+}
+
+func describe(silence Silence) string {
+	monitor := silence.Monitor
+	if monitor == "" {
+		monitor = "all monitors"
+	}
+	if silence.Key == "" {
+		return monitor
+	}
+	return fmt.Sprintf("%v %v", monitor, silence.Key)
+}
@@ -0,0 +1,101 @@
+package silence
+
+import (
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2/bson"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryStore struct {
+	mu       sync.Mutex
+	silences map[string]*Silence
+}
+
+func (s *memoryStore) IsSilenced(monitor, key string) (bool, error) { return false, nil }
+func (s *memoryStore) addSilence(silence *Silence) error            { return nil }
+func (s *memoryStore) getActive() ([]Silence, error)                { return nil, nil }
+
+func (s *memoryStore) endSilence(id string, end time.Time) (Silence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	silence, ok := s.silences[id]
+	if !ok || !silence.End.After(end) {
+		return Silence{}, ErrNotActive
+	}
+	silence.End = end
+	return *silence, nil
+}
+
+func (s *memoryStore) getEnded() (result []Silence, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, silence := range s.silences {
+		if !silence.End.After(time.Now()) && !silence.Announced {
+			result = append(result, *silence)
+		}
+	}
+	return
+}
+
+func (s *memoryStore) claimAnnouncement(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	silence := s.silences[id]
+	if silence.Announced {
+		return false, nil
+	}
+	silence.Announced = true
+	return true, nil
+}
+
+type countingAnnouncer struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (a *countingAnnouncer) Announce(ctx context.Context, monitor string, msg string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.count++
+}
+
+func TestDelete(t *testing.T) {
+	active := Silence{ID: bson.NewObjectId(), Monitor: "RDC*", End: time.Now().Add(time.Hour)}
+	ended := Silence{ID: bson.NewObjectId(), Monitor: "RDC*", End: time.Now().Add(-time.Hour), Announced: true}
+
+	tests := []struct {
+		name          string
+		id            string
+		err           error
+		announcements int
+	}{
+		{name: "active", id: active.ID.Hex(), announcements: 1},
+		{name: "already ended", id: ended.ID.Hex(), err: ErrNotActive},
+		{name: "unknown", id: bson.NewObjectId().Hex(), err: ErrNotActive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, e := active, ended
+			store := &memoryStore{silences: map[string]*Silence{a.ID.Hex(): &a, e.ID.Hex(): &e}}
+			announcer := &countingAnnouncer{}
+			s := &service{store: store, announcer: announcer}
+
+			err := s.Delete(context.Background(), tt.id)
+			if err != tt.err {
+				t.Fatalf("Delete() error = %v, want %v", err, tt.err)
+			}
+
+			//The background announcer sees the deleted silence as ended, but must not announce it again
+			ended, _ := store.getEnded()
+			for _, silence := range ended {
+				s.end(context.Background(), silence)
+			}
+			if announcer.count != tt.announcements {
+				t.Errorf("%v announcements, want %v", announcer.count, tt.announcements)
+			}
+		})
+	}
+}
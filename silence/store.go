@@ -0,0 +1,105 @@
+package silence
+
+import (
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"path"
+	"time"
+)
+
+type Store interface {
+	// IsSilenced checks if there is an active silence matching the monitor and key
+	IsSilenced(monitor, key string) (bool, error)
+
+	addSilence(s *Silence) error
+	getActive() ([]Silence, error)
+	// endSilence ends the silence if it is still active, and returns ErrNotActive if it is not
+	endSilence(id string, end time.Time) (Silence, error)
+	getEnded() ([]Silence, error)
+	// claimAnnouncement marks the silence as announced. It is false if it was already announced
+	claimAnnouncement(id string) (bool, error)
+}
+
+func NewMongoStore(db *mgo.Database) Store {
+	return &mongo{db: db}
+}
+
+type mongo struct {
+	db *mgo.Database
+}
+
+func (s mongo) IsSilenced(monitor, key string) (bool, error) {
+	active, err := s.getActive()
+	if err != nil {
+		return false, err
+	}
+	for _, silence := range active {
+		if silence.matches(monitor, key) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s mongo) addSilence(silence *Silence) error {
+	silence.ID = bson.NewObjectId()
+	return s.db.C("silences").Insert(silence)
+}
+
+func (s mongo) getActive() (result []Silence, err error) {
+	now := time.Now()
+	err = s.db.C("silences").Find(bson.M{"start": bson.M{"$lte": now}, "end": bson.M{"$gt": now}}).Sort("end").All(&result)
+	return
+}
+
+func (s mongo) endSilence(id string, end time.Time) (silence Silence, err error) {
+	if !bson.IsObjectIdHex(id) {
+		return silence, ErrNotActive
+	}
+	_, err = s.db.C("silences").Find(bson.M{"_id": bson.ObjectIdHex(id), "end": bson.M{"$gt": end}}).
+		Apply(mgo.Change{Update: bson.M{"$set": bson.M{"end": end}}, ReturnNew: true}, &silence)
+	if err == mgo.ErrNotFound {
+		err = ErrNotActive
+	}
+	return
+}
+
+// getEnded returns the silences that have ended, but have not been announced as ended yet
+func (s mongo) getEnded() (result []Silence, err error) {
+	err = s.db.C("silences").Find(bson.M{"end": bson.M{"$lte": time.Now()}, "announced": false}).All(&result)
+	return
+}
+
+func (s mongo) claimAnnouncement(id string) (bool, error) {
+	err := s.db.C("silences").Update(bson.M{"_id": bson.ObjectIdHex(id), "announced": false}, bson.M{"$set": bson.M{"announced": true}})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+/*
+Silence stops alerts and callouts for the monitors and keys matching the patterns between Start and End. The patterns
+are shell style globs, so "RDC*" matches every monitor starting with RDC, and an empty pattern matches everything.
+*/
+type Silence struct {
+	ID        bson.ObjectId `json:"id" bson:"_id"`
+	Monitor   string        `json:"monitor"`
+	Key       string        `json:"key"`
+	Reason    string        `json:"reason"`
+	Start     time.Time     `json:"start"`
+	End       time.Time     `json:"end"`
+	Announced bool          `json:"-"`
+}
+
+func (s Silence) matches(monitor, key string) bool {
+	return match(s.Monitor, monitor) && match(s.Key, key)
+}
+
+func match(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
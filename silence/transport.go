@@ -0,0 +1,41 @@
+package silence
+
+import (
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/weAutomateEverything/go2hal/gokit"
+	"net/http"
+)
+
+func MakeHandler(service Service, logger kitlog.Logger) http.Handler {
+	opts := gokit.GetServerOpts(logger, nil)
+
+	create := kithttp.NewServer(makeCreateSilenceEndpoint(service), decodeCreateSilence, gokit.EncodeResponse, opts...)
+	list := kithttp.NewServer(makeListSilencesEndpoint(service), decodeEmpty, gokit.EncodeResponse, opts...)
+	remove := kithttp.NewServer(makeDeleteSilenceEndpoint(service), decodeSilenceId, gokit.EncodeResponse, opts...)
+	r := mux.NewRouter()
+
+	r.Handle("/monitor/silence", create).Methods("POST")
+	r.Handle("/monitor/silence", list).Methods("GET")
+	r.Handle("/monitor/silence/{id}", remove).Methods("DELETE")
+
+	return r
+}
+
+func decodeCreateSilence(_ context.Context, r *http.Request) (interface{}, error) {
+	var req createSilenceRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	return req, err
+}
+
+func decodeEmpty(_ context.Context, r *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+func decodeSilenceId(_ context.Context, r *http.Request) (interface{}, error) {
+	return mux.Vars(r)["id"], nil
+}
@@ -22,7 +22,10 @@ type sinkBinMonitor struct {
 	anomaly anomaly.Service
 }
 
-func (m sinkBinMonitor) CheckResponse(ctx context.Context, req [][]string) (response []monitor.Response, err error) {
+func (m sinkBinMonitor) CheckResponse(ctx context.Context, check monitor.Check, req [][]string) (response []monitor.Response, err error) {
+	if len(req) == 0 {
+		return
+	}
 
 	b, err := json.Marshal(req)
 	if err != nil {
@@ -5,7 +5,6 @@ import (
 	"github.com/weAutomateEverything/prognosisHalBot/anomaly"
 	"github.com/weAutomateEverything/prognosisHalBot/monitor"
 	"golang.org/x/net/context"
-	"log"
 	"net/http"
 	"os"
 	"strconv"
@@ -18,6 +17,10 @@ type sourceSinkMonitor struct {
 	anomaly anomaly.Service
 }
 
+/*
+NewSourceSinkMonitor checks the nodes against the business hours and connection limits uploaded to the store. The
+connection counts it sees are kept in the History of the Check, like the samples of the other monitor types.
+*/
 func NewSourceSinkMonitor(store Store) monitor.Monitor {
 	return &sourceSinkMonitor{
 		store:   store,
@@ -29,18 +32,20 @@ func (s sourceSinkMonitor) GetName() string {
 	return "SourceSink"
 }
 
-func (s sourceSinkMonitor) CheckResponse(ctx context.Context, input [][]string) (response []monitor.Response, err error) {
+func (s sourceSinkMonitor) CheckResponse(ctx context.Context, check monitor.Check, input [][]string) (response []monitor.Response, err error) {
 	for _, row := range input {
-		node, failed, msg := s.checkConnected(row)
+		node, failed, msg := s.checkConnected(check, row)
 		response = append(response, monitor.Response{
 			Key:        node,
 			Failure:    failed,
 			FailureMsg: msg,
 		})
 	}
+	var connections []monitor.ConnectionRecord
 	for _, row := range input {
-		node, failed, msg := s.checkMaxConnections(ctx, row)
+		node, failed, msg, count := s.checkMaxConnections(ctx, check, row)
 		if node != "" {
+			connections = append(connections, monitor.ConnectionRecord{Node: node, Connections: count})
 			response = append(response, monitor.Response{
 				Key:        node + "-Connections",
 				Failure:    failed,
@@ -48,10 +53,13 @@ func (s sourceSinkMonitor) CheckResponse(ctx context.Context, input [][]string)
 			})
 		}
 	}
+	if len(connections) > 0 {
+		check.History.SaveConnections(connections)
+	}
 	return
 }
 
-func (s sourceSinkMonitor) checkConnected(row []string) (node string, failure bool, failuremsg string) {
+func (s sourceSinkMonitor) checkConnected(check monitor.Check, row []string) (node string, failure bool, failuremsg string) {
 	node = strings.ToUpper(row[0])
 	for i := 0; i < 10; i++ {
 		node = strings.Replace(node, strconv.FormatInt(int64(i), 10), "", -1)
@@ -61,38 +69,39 @@ func (s sourceSinkMonitor) checkConnected(row []string) (node string, failure bo
 		return
 	}
 
-	log.Printf("%v detected as down", node)
+	check.Log.Printf("%v detected as down", node)
 
 	for _, times := range s.store.GetNodeTimes() {
 		if strings.Index(times.Nodename, node) != -1 {
-			if s.checkSend(times) {
+			if s.checkSend(check, times) {
 				failure = true
 				failuremsg = fmt.Sprintf("Node %v has been detected as being unavalable. ", node)
-				log.Println(failuremsg)
+				check.Log.Println(failuremsg)
 				return
 			} else {
-				log.Printf("Node %v found to be outside of critical window", node)
+				check.Log.Printf("Node %v found to be outside of critical window", node)
 				return
 			}
 		}
 	}
 	failure = true
 	failuremsg = fmt.Sprintf("Node %v has been detected as being down, however I cannot find  a record in the database that lets me know if this is critical or not, so I am treating it as critical", node)
-	log.Println(failuremsg)
+	check.Log.Println(failuremsg)
 	return
 }
 
-func (s sourceSinkMonitor) checkMaxConnections(ctx context.Context, row []string) (node string, failure bool, failuremsg string) {
+func (s sourceSinkMonitor) checkMaxConnections(ctx context.Context, check monitor.Check, row []string) (node string, failure bool, failuremsg string, count int) {
 	for _, max := range s.store.getMaxConnections() {
 		if row[0] == max.Nodename {
 			node = row[0]
 			v := row[2]
 			connections, err := strconv.ParseInt(v, 10, 64)
 			if err != nil {
-				log.Printf("unable to parse %v as a int for max value", v)
+				check.Log.Printf("unable to parse %v as a int for max value", v)
 				continue
 			}
-			failed, msg := s.saveAndValidate(ctx, max.Nodename, int(connections))
+			count = int(connections)
+			failed, msg := s.saveAndValidate(ctx, check, max.Nodename, count)
 			if failed {
 				failure = true
 				failuremsg = failuremsg + "\n unusual number of connections detected. \n" + msg
@@ -107,28 +116,28 @@ func (s sourceSinkMonitor) checkMaxConnections(ctx context.Context, row []string
 	return
 }
 
-func (s sourceSinkMonitor) checkSend(node nodeHours) bool {
-	log.Printf("CHecking if we should send %v, business hours %v, busienss impact %v, after hours %v, after hours impact %v",
+func (s sourceSinkMonitor) checkSend(check monitor.Check, node nodeHours) bool {
+	check.Log.Printf("CHecking if we should send %v, business hours %v, busienss impact %v, after hours %v, after hours impact %v",
 		node.Nodename, node.BusinessHours, node.BusinessHoursImpact, node.AfterHours, node.AfterHoursImpact)
 	if node.BusinessHours == "24 X 7" {
 		return node.BusinessHoursImpact == "Critical"
 	}
-	if s.checkTime(node.BusinessHours, node.BusinessHoursImpact) {
+	if s.checkTime(check, node.BusinessHours, node.BusinessHoursImpact) {
 		return true
 	}
 
-	return s.checkTime(node.AfterHours, node.AfterHoursImpact)
+	return s.checkTime(check, node.AfterHours, node.AfterHoursImpact)
 
 }
 
-func (s sourceSinkMonitor) saveAndValidate(ctx context.Context, nodename string, count int) (bool, string) {
+func (s sourceSinkMonitor) saveAndValidate(ctx context.Context, check monitor.Check, nodename string, count int) (bool, string) {
 
 	failed, _, msg, score, _ := s.anomaly.Analyse("connections_"+nodename, float64(count))
 
 	resp, err := http.Post(fmt.Sprintf("%v/write?db=prognosis", os.Getenv("KAPACITOR_URL")),
 		"application/text", strings.NewReader(fmt.Sprintf("connections,node=%v value=%v,score=%v", nodename, count, score)))
 	if err != nil {
-		log.Println(err)
+		check.Log.Println(err)
 	} else {
 		resp.Body.Close()
 	}
@@ -137,9 +146,9 @@ func (s sourceSinkMonitor) saveAndValidate(ctx context.Context, nodename string,
 
 }
 
-func (s sourceSinkMonitor) checkTime(hours, impact string) bool {
+func (s sourceSinkMonitor) checkTime(check monitor.Check, hours, impact string) bool {
 	if impact != "Critical" {
-		log.Printf("%v not critical", impact)
+		check.Log.Printf("%v not critical", impact)
 		return false
 	}
 	times := strings.Split(hours, "-")
@@ -150,7 +159,7 @@ func (s sourceSinkMonitor) checkTime(hours, impact string) bool {
 	endHour, _ := strconv.Atoi(strings.Split(endTime, "H")[0])
 	now := time.Now().Hour()
 
-	log.Printf("checking %v, against times %v and %v", now, startHour, endHour)
+	check.Log.Printf("checking %v, against times %v and %v", now, startHour, endHour)
 	if endHour > startHour {
 		return now >= startHour && now < endHour
 	} else {